@@ -1,12 +1,18 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"math"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
+	"github.com/go-playground/validator/v10"
 	"github.com/spf13/viper"
 )
 
@@ -69,6 +75,95 @@ func WithDefault(defaults map[string]interface{}) Option {
 	}
 }
 
+// WithDefaultStruct sets default configuration values from the fields of a
+// struct or struct pointer. Keys come from the mapstructure tag, then the json
+// tag, then the lowercased field name; nested structs become nested keys.
+func WithDefaultStruct(v interface{}) Option {
+	return func(c *Config) {
+		defaults, err := structToMap(reflect.ValueOf(v))
+		if err != nil {
+			c.v.Set("error", fmt.Errorf("failed to convert defaults struct: %w", err))
+			return
+		}
+		WithDefault(defaults)(c)
+	}
+}
+
+// structToMap converts the struct v into a map keyed by field tags. Unexported
+// fields, fields tagged "-" and nil pointers are skipped; embedded structs
+// without a tag are flattened into the parent map.
+func structToMap(v reflect.Value) (map[string]interface{}, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, fmt.Errorf("nil value")
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct, got %s", v.Kind())
+	}
+
+	out := make(map[string]interface{})
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, squash := fieldKey(field)
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		for fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				break
+			}
+			fv = fv.Elem()
+		}
+		if fv.Kind() == reflect.Ptr {
+			continue
+		}
+		if fv.Kind() == reflect.Struct && fv.Type() != reflect.TypeOf(time.Time{}) {
+			nested, err := structToMap(fv)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", field.Name, err)
+			}
+			if squash {
+				for k, val := range nested {
+					out[k] = val
+				}
+				continue
+			}
+			out[name] = nested
+			continue
+		}
+		out[name] = fv.Interface()
+	}
+	return out, nil
+}
+
+// fieldKey returns the config key for field and whether an embedded struct
+// field should be flattened into its parent.
+func fieldKey(field reflect.StructField) (string, bool) {
+	for _, tagName := range []string{"mapstructure", "json"} {
+		tag, ok := field.Tag.Lookup(tagName)
+		if !ok {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		for _, opt := range parts[1:] {
+			if opt == "squash" {
+				return "", true
+			}
+		}
+		if parts[0] != "" {
+			return parts[0], false
+		}
+	}
+	return strings.ToLower(field.Name), field.Anonymous
+}
+
 // WithEnv loads configuration from environment variables.
 func WithEnv(prefix string) Option {
 	return func(c *Config) {
@@ -124,37 +219,84 @@ func New(opts ...Option) (*Config, error) {
 
 // applyDefaults applies default values from struct tags.
 func (c *Config) applyDefaults() error {
-	v := reflect.ValueOf(&c.configStruct).Elem()
+	return setDefaults(reflect.ValueOf(&c.configStruct).Elem())
+}
+
+// setDefaults sets zero-valued fields of the struct v to the value of their
+// default tag, recursing into nested structs.
+func setDefaults(v reflect.Value) error {
 	t := v.Type()
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
+		f := v.Field(i)
+		if f.Kind() == reflect.Struct && field.Type != reflect.TypeOf(time.Time{}) {
+			if f.CanSet() {
+				if err := setDefaults(f); err != nil {
+					return err
+				}
+			}
+			continue
+		}
 		defaultVal := field.Tag.Get("default")
 		if defaultVal == "" {
 			continue
 		}
-		f := v.Field(i)
 		if !f.CanSet() {
 			return fmt.Errorf("cannot set field %s: not addressable", field.Name)
 		}
 		if !f.IsZero() {
 			continue
 		}
-		switch f.Kind() {
-		case reflect.String:
-			f.SetString(defaultVal)
-		case reflect.Bool:
-			f.SetBool(defaultVal == "true")
-		case reflect.Map:
-			if defaultVal == "" {
-				f.Set(reflect.MakeMap(f.Type()))
-			}
-		default:
-			return fmt.Errorf("unsupported field type for default: %v", f.Kind())
+		if err := setDefault(f, defaultVal); err != nil {
+			return fmt.Errorf("invalid default for field %s: %w", field.Name, err)
 		}
 	}
 	return nil
 }
 
+// setDefault parses s into the field f according to its kind.
+func setDefault(f reflect.Value, s string) error {
+	if f.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		f.SetInt(int64(d))
+		return nil
+	}
+	switch f.Kind() {
+	case reflect.String:
+		f.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, f.Type().Bits())
+		if err != nil {
+			return err
+		}
+		f.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported field type for default: %v", f.Kind())
+	}
+	return nil
+}
+
 // validateRequiredFields checks for required fields in ConfigStruct.
 func (c *Config) validateRequiredFields() error {
 	v := reflect.ValueOf(c.configStruct)
@@ -172,13 +314,54 @@ func (c *Config) validateRequiredFields() error {
 	return nil
 }
 
-// Get retrieves a configuration value by key.
+// Get retrieves a configuration value by key. Elements of lists can be
+// addressed with numeric segments, e.g. "servers.0.host" or "servers[0].host".
+// It returns nil when the key or index does not exist.
 func (c *Config) Get(key string) interface{} {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	segments := strings.Split(indexPattern.ReplaceAllString(key, ".$1"), ".")
+	for i, segment := range segments {
+		if _, err := strconv.Atoi(segment); err == nil && i > 0 {
+			return lookupPath(c.v.Get(strings.Join(segments[:i], ".")), segments[i:])
+		}
+	}
 	return c.v.Get(key)
 }
 
+// indexPattern matches bracketed list indexes such as "[0]" in keys.
+var indexPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+// lookupPath walks value along segments, indexing lists by numeric segments
+// and maps by case-insensitive keys.
+func lookupPath(value interface{}, segments []string) interface{} {
+	for _, segment := range segments {
+		if value == nil {
+			return nil
+		}
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array:
+			i, err := strconv.Atoi(segment)
+			if err != nil || i < 0 || i >= rv.Len() {
+				return nil
+			}
+			value = rv.Index(i).Interface()
+		case reflect.Map:
+			value = nil
+			for _, k := range rv.MapKeys() {
+				if strings.EqualFold(fmt.Sprint(k.Interface()), segment) {
+					value = rv.MapIndex(k).Interface()
+					break
+				}
+			}
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
 // GetStringWithDefault retrieves a string value with a default.
 func (c *Config) GetStringWithDefault(key, defaultValue string) string {
 	c.mu.RLock()
@@ -189,13 +372,128 @@ func (c *Config) GetStringWithDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
-// GetBool retrieves a boolean value.
+// GetBool retrieves a boolean value. String values such as those read from
+// environment variables are coerced: "true", "1", "yes", "y" and "on" are true
+// (case-insensitive) and anything else is false. Unset keys return false.
 func (c *Config) GetBool(key string) bool {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
+	if s, ok := c.v.Get(key).(string); ok {
+		return parseBool(s)
+	}
 	return c.v.GetBool(key)
 }
 
+// parseBool reports whether s is a truthy string.
+func parseBool(s string) bool {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "true", "1", "yes", "y", "on", "t":
+		return true
+	default:
+		return false
+	}
+}
+
+// GetIntWithDefault retrieves an integer value with a default. Integers,
+// whole floats such as those decoded from JSON, and numeric strings such as
+// those read from environment variables are accepted. Unset keys and values
+// that are not integers return defaultValue.
+func (c *Config) GetIntWithDefault(key string, defaultValue int) int {
+	if n, ok := toInt(c.Get(key)); ok {
+		return n
+	}
+	return defaultValue
+}
+
+// GetBoolWithDefault retrieves a boolean value with a default. Strings are
+// coerced as in GetBool, with "false", "0", "no", "n", "off" and "f" read as
+// false. Unset keys and values that are neither return defaultValue.
+func (c *Config) GetBoolWithDefault(key string, defaultValue bool) bool {
+	switch v := c.Get(key).(type) {
+	case bool:
+		return v
+	case string:
+		if parseBool(v) {
+			return true
+		}
+		switch strings.ToLower(strings.TrimSpace(v)) {
+		case "false", "0", "no", "n", "off", "f":
+			return false
+		}
+	}
+	return defaultValue
+}
+
+// GetDuration retrieves a duration value, parsed as in GetDurationWithDefault,
+// so "2s" and "1500ms" are accepted. Unset keys and unparsable values return 0.
+func (c *Config) GetDuration(key string) time.Duration {
+	return c.GetDurationWithDefault(key, 0)
+}
+
+// GetDurationWithDefault retrieves a duration value with a default. Strings
+// are parsed with time.ParseDuration, e.g. "1.5s" or "300ms", and integers are
+// read as nanoseconds. Unset keys and unparsable values return defaultValue.
+func (c *Config) GetDurationWithDefault(key string, defaultValue time.Duration) time.Duration {
+	switch v := c.Get(key).(type) {
+	case time.Duration:
+		return v
+	case string:
+		if d, err := time.ParseDuration(strings.TrimSpace(v)); err == nil {
+			return d
+		}
+		return defaultValue
+	case nil:
+		return defaultValue
+	default:
+		if n, ok := toInt(v); ok {
+			return time.Duration(n)
+		}
+	}
+	return defaultValue
+}
+
+// GetEnum retrieves a string value that must be one of allowed, compared
+// case-insensitively, and returns it as spelled in allowed. Unset keys return
+// defaultValue. A value outside allowed returns defaultValue together with an
+// error naming the allowed values, so callers can warn and fall back or fail.
+func (c *Config) GetEnum(key string, allowed []string, defaultValue string) (string, error) {
+	value := c.Get(key)
+	if value == nil {
+		return defaultValue, nil
+	}
+	s := strings.TrimSpace(fmt.Sprint(value))
+	for _, a := range allowed {
+		if strings.EqualFold(s, a) {
+			return a, nil
+		}
+	}
+	return defaultValue, fmt.Errorf("invalid value %q for %s: must be one of %s", s, key, strings.Join(allowed, ", "))
+}
+
+// toInt converts integer kinds, whole floats and numeric strings to int.
+func toInt(value interface{}) (int, bool) {
+	if value == nil {
+		return 0, false
+	}
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if f != math.Trunc(f) {
+			return 0, false
+		}
+		return int(f), true
+	case reflect.String:
+		n, err := strconv.Atoi(strings.TrimSpace(rv.String()))
+		return n, err == nil
+	}
+	return 0, false
+}
+
 // GetStringMapString retrieves a map[string]string.
 func (c *Config) GetStringMapString(key string) map[string]string {
 	c.mu.RLock()
@@ -210,9 +508,68 @@ func (c *Config) GetConfigStruct() ConfigStruct {
 	return c.configStruct
 }
 
+// redactedValue replaces the values of keys passed to DumpRedacted.
+const redactedValue = "[REDACTED]"
+
+// Dump returns the effective configuration, with defaults, file and
+// environment values merged, as nested maps keyed by lowercase key segments.
+func (c *Config) Dump() map[string]interface{} {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.v.AllSettings()
+}
+
+// DumpRedacted is like Dump but masks the values of the given keys, which may
+// be nested (e.g. "db.password"). Keys that are not set are ignored.
+func (c *Config) DumpRedacted(keys ...string) map[string]interface{} {
+	settings := c.Dump()
+	for _, key := range keys {
+		parts := strings.Split(strings.ToLower(key), ".")
+		m := settings
+		for _, part := range parts[:len(parts)-1] {
+			next, ok := m[part].(map[string]interface{})
+			if !ok {
+				m = nil
+				break
+			}
+			m = next
+		}
+		if m == nil {
+			continue
+		}
+		if _, ok := m[parts[len(parts)-1]]; ok {
+			m[parts[len(parts)-1]] = redactedValue
+		}
+	}
+	return settings
+}
+
 // Unmarshal unmarshals the entire configuration into the target struct.
 func (c *Config) Unmarshal(target interface{}) error {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	return c.v.Unmarshal(target)
 }
+
+// Bind fills the struct pointed to by target: fields are first set from their
+// default tags, then overridden by configuration values matched on their
+// mapstructure tags, and finally checked against their validate tags. Decode
+// and validation errors are returned together.
+func (c *Config) Bind(target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind target must be a non-nil pointer to a struct, got %T", target)
+	}
+	if err := setDefaults(rv.Elem()); err != nil {
+		return fmt.Errorf("failed to apply defaults: %w", err)
+	}
+
+	var errs []error
+	if err := c.Unmarshal(target); err != nil {
+		errs = append(errs, fmt.Errorf("failed to decode config: %w", err))
+	}
+	if err := validator.New().Struct(target); err != nil {
+		errs = append(errs, fmt.Errorf("config validation failed: %w", err))
+	}
+	return errors.Join(errs...)
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -406,6 +407,26 @@ func TestGetBoolNonBoolean(t *testing.T) {
 	assert.False(t, v.GetBool("debug")) // Non-boolean value returns false
 }
 
+// TestGetBoolStringValues tests GetBool coerces string values and unset keys.
+func TestGetBoolStringValues(t *testing.T) {
+	cfg, err := New(WithDefault(map[string]interface{}{
+		"flag_true":  "true",
+		"flag_one":   "1",
+		"flag_yes":   "YES",
+		"flag_false": "false",
+		"flag_junk":  "not-a-boolean",
+		"flag_bool":  true,
+	}))
+	assert.NoError(t, err)
+	assert.True(t, cfg.GetBool("flag_true"))
+	assert.True(t, cfg.GetBool("flag_one"))
+	assert.True(t, cfg.GetBool("flag_yes"))
+	assert.True(t, cfg.GetBool("flag_bool"))
+	assert.False(t, cfg.GetBool("flag_false"))
+	assert.False(t, cfg.GetBool("flag_junk"))
+	assert.False(t, cfg.GetBool("unset_flag"))
+}
+
 // TestUnmarshalInvalidTarget tests Unmarshal with an invalid target.
 func TestUnmarshalInvalidTarget(t *testing.T) {
 	cfg, err := New()
@@ -519,3 +540,280 @@ func TestNestedConfig(t *testing.T) {
 	assert.Equal(t, "8080", nested.App.Config.Port)
 	assert.Equal(t, "30s", nested.App.Config.Timeout)
 }
+
+// bindTarget exercises Bind with defaults, config overrides and validation.
+type bindTarget struct {
+	Host    string        `mapstructure:"bind_host" default:"localhost" validate:"required"`
+	Port    int           `mapstructure:"bind_port" default:"8080" validate:"gt=0"`
+	Enabled bool          `mapstructure:"bind_enabled" default:"true"`
+	Timeout time.Duration `mapstructure:"bind_timeout" default:"2s"`
+}
+
+// TestBind tests defaults are applied and overridden by configuration values.
+func TestBind(t *testing.T) {
+	cfg, err := New(WithDefault(map[string]interface{}{"bind_host": "example.com"}))
+	assert.NoError(t, err)
+
+	var target bindTarget
+	assert.NoError(t, cfg.Bind(&target))
+	assert.Equal(t, bindTarget{Host: "example.com", Port: 8080, Enabled: true, Timeout: 2 * time.Second}, target)
+}
+
+// TestBindValidationError tests Bind reports failing validate tags.
+func TestBindValidationError(t *testing.T) {
+	cfg, err := New(WithDefault(map[string]interface{}{"bind_port": -1}))
+	assert.NoError(t, err)
+
+	var target bindTarget
+	err = cfg.Bind(&target)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "config validation failed")
+	assert.Contains(t, err.Error(), "Port")
+
+	assert.Error(t, cfg.Bind(target), "non-pointer target should be rejected")
+}
+
+type structDefaultsDB struct {
+	Host string `json:"host"`
+	Port int    `mapstructure:"port" json:"db_port"`
+}
+
+type structDefaults struct {
+	Name     string           `json:"name"`
+	Debug    bool             `mapstructure:"debug"`
+	Timeout  time.Duration    `json:"timeout"`
+	Database structDefaultsDB `json:"database"`
+	Secret   string           `json:"-"`
+	Region   string
+}
+
+// TestWithDefaultStruct tests defaults taken from a typed struct.
+func TestWithDefaultStruct(t *testing.T) {
+	cfg, err := New(WithDefaultStruct(&structDefaults{
+		Name:     "orders",
+		Debug:    true,
+		Timeout:  time.Second,
+		Database: structDefaultsDB{Host: "db.local", Port: 5432},
+		Secret:   "hidden",
+		Region:   "eu",
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "orders", cfg.Get("name"))
+	assert.True(t, cfg.GetBool("debug"))
+	assert.Equal(t, time.Second, cfg.Get("timeout"))
+	assert.Equal(t, "db.local", cfg.Get("database.host"))
+	assert.Equal(t, 5432, cfg.Get("database.port"))
+	assert.Nil(t, cfg.Get("secret"))
+	assert.Equal(t, "eu", cfg.Get("region"))
+
+	_, err = New(WithDefaultStruct("not a struct"))
+	assert.Error(t, err)
+}
+
+// StructDefaultsBase is exported so it can be embedded in struct defaults.
+type StructDefaultsBase struct {
+	Environment string `json:"environment"`
+}
+
+type structDefaultsNested struct {
+	StructDefaultsBase `mapstructure:",squash"`
+	Port               int               `json:"port"`
+	Cache              *structDefaultsDB `json:"cache"`
+	Replica            *structDefaultsDB `json:"replica"`
+}
+
+// TestWithDefaultStructNested tests embedded structs and pointer fields in
+// struct defaults.
+func TestWithDefaultStructNested(t *testing.T) {
+	cfg, err := New(WithDefaultStruct(structDefaultsNested{
+		StructDefaultsBase: StructDefaultsBase{Environment: "staging"},
+		Port:               8080,
+		Cache:              &structDefaultsDB{Host: "cache.local", Port: 6379},
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, "staging", cfg.Get("environment")) // Embedded struct flattened
+	assert.Equal(t, 8080, cfg.GetIntWithDefault("port", 0))
+	assert.Equal(t, "cache.local", cfg.Get("cache.host")) // Pointer dereferenced
+	assert.Equal(t, 6379, cfg.Get("cache.port"))
+	assert.Nil(t, cfg.Get("replica.host")) // Nil pointer skipped
+
+	var missing *structDefaults
+	_, err = New(WithDefaultStruct(missing))
+	assert.Error(t, err)
+}
+
+// TestWithDefaultStructAndEnv tests environment variables overriding struct
+// defaults.
+func TestWithDefaultStructAndEnv(t *testing.T) {
+	os.Setenv("CONFIG_NAME", "env-orders")
+	defer os.Unsetenv("CONFIG_NAME")
+
+	viper.Reset()
+	cfg, err := New(WithDefaultStruct(structDefaults{Name: "orders", Region: "eu"}), WithEnv("CONFIG"))
+	assert.NoError(t, err)
+	assert.Equal(t, "env-orders", cfg.GetStringWithDefault("name", "default")) // Env overrides default
+	assert.Equal(t, "eu", cfg.GetStringWithDefault("region", "default"))       // Default preserved
+}
+
+// TestDump tests the effective configuration dump and redaction.
+func TestDump(t *testing.T) {
+	os.Setenv("CONFIG_APP_NAME", "env-app")
+	defer os.Unsetenv("CONFIG_APP_NAME")
+
+	cfg, err := New(WithDefault(map[string]interface{}{
+		"app.name":    "default-app",
+		"app.port":    "8080",
+		"db.password": "s3cret",
+		"api_key":     "abc",
+	}), WithEnv("CONFIG"))
+	assert.NoError(t, err)
+
+	dump := cfg.Dump()
+	app := dump["app"].(map[string]interface{})
+	assert.Equal(t, "env-app", app["name"]) // Env merged over default
+	assert.Equal(t, "8080", app["port"])
+	assert.Equal(t, "s3cret", dump["db"].(map[string]interface{})["password"])
+
+	redacted := cfg.DumpRedacted("DB.Password", "api_key", "missing.key")
+	assert.Equal(t, "[REDACTED]", redacted["db"].(map[string]interface{})["password"])
+	assert.Equal(t, "[REDACTED]", redacted["api_key"])
+	assert.Equal(t, "env-app", redacted["app"].(map[string]interface{})["name"])
+	assert.Equal(t, "s3cret", cfg.Get("db.password"), "redaction must not change the config")
+}
+
+// TestGetIndexed tests indexed access to list elements.
+func TestGetIndexed(t *testing.T) {
+	content := []byte(`
+servers:
+  - host: alpha.internal
+    Port: 8080
+  - host: beta.internal
+    port: 9090
+cluster:
+  brokers:
+    - kafka-1:9092
+    - kafka-2:9092
+`)
+	tmpfile, err := os.CreateTemp("", "config*.yaml")
+	assert.NoError(t, err)
+	defer os.Remove(tmpfile.Name())
+	_, err = tmpfile.Write(content)
+	assert.NoError(t, err)
+	tmpfile.Close()
+
+	cfg, err := New(WithFilepath(tmpfile.Name()))
+	assert.NoError(t, err)
+	assert.Equal(t, "alpha.internal", cfg.Get("servers.0.host"))
+	assert.Equal(t, "beta.internal", cfg.Get("servers[1].host"))
+	assert.Equal(t, 8080, cfg.Get("servers.0.port"))
+	assert.Equal(t, map[string]interface{}{"host": "beta.internal", "port": 9090}, cfg.Get("servers.1"))
+	assert.Equal(t, "kafka-2:9092", cfg.Get("cluster.brokers.1"))
+	assert.Equal(t, "kafka-1:9092", cfg.Get("cluster.brokers[0]"))
+	assert.Nil(t, cfg.Get("servers.2.host"))
+	assert.Nil(t, cfg.Get("servers.0.missing"))
+	assert.Nil(t, cfg.Get("cluster.brokers.0.host"))
+}
+
+// TestTypedGettersWithDefault tests the typed getters with present, absent and
+// wrongly typed keys.
+func TestTypedGettersWithDefault(t *testing.T) {
+	cfg, err := New(WithDefault(map[string]interface{}{
+		"port":         8080,
+		"port_float":   9090.0,
+		"port_string":  "7070",
+		"ratio":        1.5,
+		"name":         "svc",
+		"enabled":      true,
+		"enabled_env":  "yes",
+		"disabled_env": "off",
+		"timeout":      "1.5s",
+		"timeout_ns":   int64(time.Second),
+		"timeout_dur":  2 * time.Second,
+		"list":         []interface{}{"a"},
+	}))
+	assert.NoError(t, err)
+
+	// Present
+	assert.Equal(t, 8080, cfg.GetIntWithDefault("port", 1))
+	assert.Equal(t, 9090, cfg.GetIntWithDefault("port_float", 1))
+	assert.Equal(t, 7070, cfg.GetIntWithDefault("port_string", 1))
+	assert.True(t, cfg.GetBoolWithDefault("enabled", false))
+	assert.True(t, cfg.GetBoolWithDefault("enabled_env", false))
+	assert.False(t, cfg.GetBoolWithDefault("disabled_env", true))
+	assert.Equal(t, 1500*time.Millisecond, cfg.GetDurationWithDefault("timeout", time.Minute))
+	assert.Equal(t, time.Second, cfg.GetDurationWithDefault("timeout_ns", time.Minute))
+	assert.Equal(t, 2*time.Second, cfg.GetDurationWithDefault("timeout_dur", time.Minute))
+
+	// Absent
+	assert.Equal(t, 42, cfg.GetIntWithDefault("missing", 42))
+	assert.True(t, cfg.GetBoolWithDefault("missing", true))
+	assert.Equal(t, time.Minute, cfg.GetDurationWithDefault("missing", time.Minute))
+
+	// Wrong type
+	assert.Equal(t, 42, cfg.GetIntWithDefault("name", 42))
+	assert.Equal(t, 42, cfg.GetIntWithDefault("ratio", 42))
+	assert.Equal(t, 42, cfg.GetIntWithDefault("list", 42))
+	assert.True(t, cfg.GetBoolWithDefault("name", true))
+	assert.True(t, cfg.GetBoolWithDefault("port", true))
+	assert.Equal(t, time.Minute, cfg.GetDurationWithDefault("name", time.Minute))
+	assert.Equal(t, time.Minute, cfg.GetDurationWithDefault("enabled", time.Minute))
+}
+
+// TestTypedGettersWithDefaultFromEnv tests the typed getters coerce string
+// values from environment variables.
+func TestTypedGettersWithDefaultFromEnv(t *testing.T) {
+	os.Setenv("CONFIG_WORKERS", "8")
+	os.Setenv("CONFIG_VERBOSE", "true")
+	os.Setenv("CONFIG_POLL_INTERVAL", "250ms")
+	defer os.Unsetenv("CONFIG_WORKERS")
+	defer os.Unsetenv("CONFIG_VERBOSE")
+	defer os.Unsetenv("CONFIG_POLL_INTERVAL")
+
+	cfg, err := New(WithEnv("CONFIG"))
+	assert.NoError(t, err)
+	assert.Equal(t, 8, cfg.GetIntWithDefault("workers", 1))
+	assert.True(t, cfg.GetBoolWithDefault("verbose", false))
+	assert.Equal(t, 250*time.Millisecond, cfg.GetDurationWithDefault("poll_interval", time.Second))
+}
+
+// TestGetDuration tests GetDuration parses defaults and environment variables
+// and returns zero for missing or invalid values.
+func TestGetDuration(t *testing.T) {
+	os.Setenv("CONFIG_RETRY_DELAY", "1500ms")
+	defer os.Unsetenv("CONFIG_RETRY_DELAY")
+
+	cfg, err := New(WithDefault(map[string]interface{}{
+		"timeout": "2s",
+		"name":    "alice",
+	}), WithEnv("CONFIG"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Second, cfg.GetDuration("timeout"))
+	assert.Equal(t, 1500*time.Millisecond, cfg.GetDuration("retry_delay"))
+	assert.Equal(t, time.Duration(0), cfg.GetDuration("name"))
+	assert.Equal(t, time.Duration(0), cfg.GetDuration("missing"))
+}
+
+// TestGetEnum tests GetEnum normalizes case, falls back to the default and
+// rejects values outside the allowed set.
+func TestGetEnum(t *testing.T) {
+	cfg, err := New(WithDefault(map[string]interface{}{
+		"kafka_compression": "Snappy",
+		"kafka_balancer":    "random",
+	}))
+	assert.NoError(t, err)
+	allowed := []string{"none", "gzip", "snappy", "lz4", "zstd"}
+
+	v, err := cfg.GetEnum("kafka_compression", allowed, "none")
+	assert.NoError(t, err)
+	assert.Equal(t, "snappy", v)
+
+	v, err = cfg.GetEnum("missing", allowed, "none")
+	assert.NoError(t, err)
+	assert.Equal(t, "none", v)
+
+	v, err = cfg.GetEnum("kafka_balancer", []string{"least_bytes", "hash", "round_robin"}, "least_bytes")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid value "random" for kafka_balancer`)
+	assert.Contains(t, err.Error(), "least_bytes, hash, round_robin")
+	assert.Equal(t, "least_bytes", v)
+}
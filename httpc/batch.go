@@ -0,0 +1,70 @@
+package httpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/T-Prohmpossadhorn/go-core/logger"
+)
+
+// Request describes one call made by CallAll.
+type Request struct {
+	Method string
+	URL    string
+	// Input, when not nil, is sent as the JSON request body.
+	Input interface{}
+	// Output, when not nil, receives the decoded JSON response.
+	Output  interface{}
+	Options []CallOption
+}
+
+// Result is the outcome of the Request with the same index in CallAll.
+type Result struct {
+	// Output is the Request's Output, filled in when Err is nil.
+	Output interface{}
+	Err    error
+}
+
+// CallAll sends requests concurrently with CallContext, using at most
+// http_client_batch_workers requests in flight, and returns their results in
+// the same order. A failed request does not stop the others. Requests not
+// started before ctx is done fail with the context error.
+func (h *HTTPClient) CallAll(ctx context.Context, requests []Request) []Result {
+	results := make([]Result, len(requests))
+	workers := h.config.BatchWorkers
+	if workers > len(requests) {
+		workers = len(requests)
+	}
+	logger.InfoContext(ctx, "Sending batch", logger.Int("requests", len(requests)), logger.Int("workers", workers))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				req := requests[i]
+				err := h.CallContext(ctx, req.Method, req.URL, req.Input, req.Output, req.Options...)
+				results[i] = Result{Output: req.Output, Err: err}
+			}
+		}()
+	}
+
+	sent := 0
+send:
+	for ; sent < len(requests); sent++ {
+		select {
+		case jobs <- sent:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	for i := sent; i < len(requests); i++ {
+		results[i] = Result{Err: fmt.Errorf("request not sent: %w", ctx.Err())}
+	}
+	wg.Wait()
+	return results
+}
@@ -0,0 +1,81 @@
+package httpc
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// methodPath returns the route of m relative to the service prefix: its Path
+// when set, otherwise its Name.
+func methodPath(m MethodInfo) string {
+	if m.Path != "" {
+		return strings.TrimPrefix(m.Path, "/")
+	}
+	return m.Name
+}
+
+// pathParams returns the names of the ":param" segments in path.
+func pathParams(path string) []string {
+	var params []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, ":") {
+			params = append(params, segment[1:])
+		}
+	}
+	return params
+}
+
+// bindInput populates the struct pointed to by input from every part of the
+// request: path parameters into `uri` fields, query parameters into `form`
+// fields and, for methods other than GET and HEAD, the JSON body into `json`
+// fields. Later sources override earlier ones. GET and HEAD inputs are always
+// bound from the query, even an empty one, so `form` defaults and validation
+// apply; other methods bind the query only when there is one. On failure it
+// returns the name of the source that could not be bound.
+func bindInput(c *gin.Context, method string, input interface{}) (string, error) {
+	if len(c.Params) > 0 {
+		if err := c.ShouldBindUri(input); err != nil {
+			return "Path", err
+		}
+	}
+	if method == http.MethodGet || method == http.MethodHead || c.Request.URL.RawQuery != "" {
+		if err := c.ShouldBindQuery(input); err != nil {
+			return "Query", err
+		}
+	}
+	if method != http.MethodGet && method != http.MethodHead && c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(input); err != nil {
+			return "JSON", err
+		}
+	}
+	return "", nil
+}
+
+// isJSONContentType reports whether contentType is application/json or a
+// +json type such as application/problem+json.
+func isJSONContentType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// unsupportedContentType returns the Content-Type of a request whose body m
+// would bind as JSON although it is not JSON, or an empty string when the
+// request is acceptable. Methods that set a non-JSON ContentType, requests
+// without a body or a Content-Type, and GET and HEAD requests are accepted.
+func unsupportedContentType(c *gin.Context, m MethodInfo) string {
+	if m.ContentType != "" && !isJSONContentType(m.ContentType) {
+		return ""
+	}
+	method := strings.ToUpper(m.HTTPMethod)
+	if method == http.MethodGet || method == http.MethodHead || c.Request.ContentLength == 0 {
+		return ""
+	}
+	contentType := c.GetHeader("Content-Type")
+	if contentType == "" || isJSONContentType(contentType) {
+		return ""
+	}
+	return contentType
+}
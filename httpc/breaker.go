@@ -0,0 +1,80 @@
+package httpc
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by HTTPClient calls while the circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit open")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// circuitBreaker opens after threshold consecutive failures and lets a single
+// probe call through once resetTimeout has elapsed.
+type circuitBreaker struct {
+	mu           sync.Mutex
+	threshold    int
+	resetTimeout time.Duration
+	state        breakerState
+	failures     int
+	openedAt     time.Time
+}
+
+func newCircuitBreaker(threshold int, resetTimeout time.Duration) *circuitBreaker {
+	return &circuitBreaker{threshold: threshold, resetTimeout: resetTimeout}
+}
+
+// allow reports whether a call may proceed, moving an open breaker to
+// half-open once the reset timeout has passed.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < b.resetTimeout {
+			return false
+		}
+		b.state = breakerHalfOpen
+		return true
+	case breakerHalfOpen:
+		// A probe is already in flight
+		return false
+	default:
+		return true
+	}
+}
+
+// record updates the breaker with the outcome of a call.
+func (b *circuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if success {
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// release ends a call whose outcome says nothing about the upstream, such as
+// one canceled by the caller. A half-open breaker goes back to open with its
+// reset timeout already elapsed, so the next call probes instead.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+	}
+}
@@ -0,0 +1,138 @@
+package httpc
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithCache enables in-memory caching of successful GET responses keyed by
+// URL. Responses stay fresh for ttl unless Cache-Control max-age says
+// otherwise; stale entries with an ETag are revalidated with If-None-Match and
+// stale entries without one are dropped. Calls with per-call headers, such as
+// a caller's Authorization, bypass the cache, and a response is only served
+// to requests that match it on the headers named by its Vary header.
+func WithCache(ttl time.Duration) ClientOption {
+	return func(h *HTTPClient) {
+		h.cache = newResponseCache(ttl)
+	}
+}
+
+type cacheEntry struct {
+	body    []byte
+	etag    string
+	expires time.Time
+	vary    map[string]string // request header values the response varies on
+}
+
+type responseCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*cacheEntry
+}
+
+func newResponseCache(ttl time.Duration) *responseCache {
+	return &responseCache{ttl: ttl, entries: map[string]*cacheEntry{}}
+}
+
+// get returns the entry for url matching the request headers reqHeader, if
+// any, and whether it is still fresh. A stale entry without an ETag cannot be
+// revalidated and is dropped.
+func (c *responseCache) get(url string, reqHeader http.Header) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[url]
+	if !ok {
+		return nil, false
+	}
+	fresh := time.Now().Before(e.expires)
+	if !fresh && e.etag == "" {
+		delete(c.entries, url)
+		return nil, false
+	}
+	for name, value := range e.vary {
+		if reqHeader.Get(name) != value {
+			return nil, false
+		}
+	}
+	return e, fresh
+}
+
+// store caches body for url according to the response headers. reqHeader
+// holds the request headers, of which those named by Vary are kept to match
+// later requests against.
+func (c *responseCache) store(url string, reqHeader http.Header, body []byte, header http.Header) {
+	ttl, ok := c.freshness(header)
+	etag := header.Get("ETag")
+	if !ok || (ttl <= 0 && etag == "") {
+		// An entry that is stale at once and cannot be revalidated is useless
+		return
+	}
+	vary := map[string]string{}
+	for _, v := range header.Values("Vary") {
+		for _, name := range strings.Split(v, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" {
+				return
+			}
+			if name != "" {
+				vary[name] = reqHeader.Get(name)
+			}
+		}
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for u, e := range c.entries {
+		if e.etag == "" && !now.Before(e.expires) {
+			delete(c.entries, u)
+		}
+	}
+	c.entries[url] = &cacheEntry{
+		body:    body,
+		etag:    etag,
+		expires: now.Add(ttl),
+		vary:    vary,
+	}
+}
+
+// refresh extends a revalidated entry after a 304 response.
+func (c *responseCache) refresh(url string, header http.Header) {
+	ttl, ok := c.freshness(header)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, found := c.entries[url]
+	if !found {
+		return
+	}
+	if !ok {
+		delete(c.entries, url)
+		return
+	}
+	e.expires = time.Now().Add(ttl)
+}
+
+// freshness derives how long a response may be served from cache. It returns
+// false when Cache-Control forbids storing the response.
+func (c *responseCache) freshness(header http.Header) (time.Duration, bool) {
+	ttl, noCache := c.ttl, false
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		directive = strings.ToLower(strings.TrimSpace(directive))
+		switch {
+		case directive == "no-store":
+			return 0, false
+		case directive == "no-cache":
+			noCache = true
+		case strings.HasPrefix(directive, "max-age="):
+			if secs, err := strconv.Atoi(strings.TrimPrefix(directive, "max-age=")); err == nil {
+				ttl = time.Duration(secs) * time.Second
+			}
+		}
+	}
+	if noCache {
+		ttl = 0
+	}
+	return ttl, true
+}
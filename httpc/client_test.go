@@ -1,8 +1,19 @@
 package httpc
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/T-Prohmpossadhorn/go-core/config"
 	"github.com/T-Prohmpossadhorn/go-core/logger"
@@ -178,3 +189,906 @@ func TestHTTPClient(t *testing.T) {
 		require.Contains(t, err.Error(), "invalid HTTP method: INVALID")
 	})
 }
+
+func TestHTTPClientDefaultHeaders(t *testing.T) {
+	var got http.Header
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"http_client_default_headers": map[string]interface{}{
+			"User-Agent": "go-core",
+			"X-Tenant":   "acme",
+		},
+	}))
+	require.NoError(t, err)
+
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, client.Call("GET", ts.URL, nil, nil))
+	require.Equal(t, "go-core", got.Get("User-Agent"))
+	require.Equal(t, "acme", got.Get("X-Tenant"))
+
+	require.NoError(t, client.Call("GET", ts.URL, nil, nil, WithHeader("User-Agent", "custom")))
+	require.Equal(t, "custom", got.Get("User-Agent"))
+	require.Equal(t, "acme", got.Get("X-Tenant"))
+
+	require.NoError(t, client.Call("GET", ts.URL, nil, nil, WithHeader("X-Tenant", "globex")))
+	require.Equal(t, []string{"globex"}, got.Values("X-Tenant"))
+	require.Equal(t, "go-core", got.Get("User-Agent"))
+}
+
+func TestHTTPClientRequestIDStableAcrossRetries(t *testing.T) {
+	var ids []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids = append(ids, r.Header.Get(RequestIDHeader))
+		if len(ids) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"http_client_max_retries":     2,
+		"http_client_disable_backoff": true,
+	}))
+	require.NoError(t, err)
+
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, client.Call("GET", ts.URL, nil, nil))
+	require.Len(t, ids, 2)
+	require.NotEmpty(t, ids[0])
+	require.Equal(t, ids[0], ids[1])
+
+	ids = nil
+	ctx := WithRequestID(context.Background(), "req-123")
+	require.NoError(t, client.CallContext(ctx, "GET", ts.URL, nil, nil))
+	require.Equal(t, []string{"req-123", "req-123"}, ids)
+}
+
+func TestHTTPClientCircuitBreaker(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"http_client_max_retries":       0,
+		"http_client_breaker_threshold": 2,
+		"http_client_breaker_reset_ms":  50,
+	}))
+	require.NoError(t, err)
+
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		err = client.Call("GET", ts.URL, nil, nil)
+		require.Error(t, err)
+		require.NotErrorIs(t, err, ErrCircuitOpen)
+	}
+	require.Equal(t, 2, hits)
+
+	err = client.Call("GET", ts.URL, nil, nil)
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.EqualError(t, err, "circuit open")
+	require.Equal(t, 2, hits)
+
+	// After the reset timeout a single probe reaches the server and, failing, reopens the breaker
+	time.Sleep(60 * time.Millisecond)
+	err = client.Call("GET", ts.URL, nil, nil)
+	require.NotErrorIs(t, err, ErrCircuitOpen)
+	require.Equal(t, 3, hits)
+	require.ErrorIs(t, client.Call("GET", ts.URL, nil, nil), ErrCircuitOpen)
+	require.Equal(t, 3, hits)
+}
+
+func TestHTTPClientCircuitBreakerIgnoresLocalFailures(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"http_client_max_retries":       0,
+		"http_client_breaker_threshold": 1,
+		"http_client_breaker_reset_ms":  50,
+	}))
+	require.NoError(t, err)
+
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	// A request canceled by the caller does not open the breaker
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = client.CallContext(ctx, "GET", ts.URL, nil, nil)
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotErrorIs(t, client.Call("GET", ts.URL, nil, nil), ErrCircuitOpen)
+	require.Equal(t, 1, hits)
+	require.ErrorIs(t, client.Call("GET", ts.URL, nil, nil), ErrCircuitOpen)
+
+	// A request that fails before reaching the server does not close a
+	// half-open breaker, and the next call probes it instead
+	time.Sleep(60 * time.Millisecond)
+	req, err := http.NewRequest("POST", ts.URL, bytes.NewReader([]byte("{}")))
+	require.NoError(t, err)
+	req.GetBody = func() (io.ReadCloser, error) { return nil, errors.New("body gone") }
+	_, err = client.Do(req)
+	require.ErrorContains(t, err, "failed to reset request body")
+	require.Equal(t, 1, hits)
+
+	require.NotErrorIs(t, client.Call("GET", ts.URL, nil, nil), ErrCircuitOpen)
+	require.Equal(t, 2, hits)
+	require.ErrorIs(t, client.Call("GET", ts.URL, nil, nil), ErrCircuitOpen)
+}
+
+func TestHTTPClientCache(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`"cached"`))
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New()
+	require.NoError(t, err)
+
+	client, err := NewHTTPClient(cfg, WithCache(50*time.Millisecond))
+	require.NoError(t, err)
+
+	var result string
+	require.NoError(t, client.Call("GET", ts.URL, nil, &result))
+	require.Equal(t, "cached", result)
+	require.Equal(t, 1, hits)
+
+	result = ""
+	require.NoError(t, client.Call("GET", ts.URL, nil, &result))
+	require.Equal(t, "cached", result)
+	require.Equal(t, 1, hits, "fresh response should be served from cache")
+
+	// Once stale, the entry is revalidated with its ETag and a 304 is a cache hit
+	time.Sleep(60 * time.Millisecond)
+	result = ""
+	require.NoError(t, client.Call("GET", ts.URL, nil, &result))
+	require.Equal(t, "cached", result)
+	require.Equal(t, 2, hits)
+}
+
+func TestHTTPClientCacheNoStore(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		w.Header().Set("Cache-Control", "no-store")
+		_, _ = w.Write([]byte(`"fresh"`))
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New()
+	require.NoError(t, err)
+
+	client, err := NewHTTPClient(cfg, WithCache(time.Minute))
+	require.NoError(t, err)
+
+	var result string
+	require.NoError(t, client.Call("GET", ts.URL, nil, &result))
+	require.NoError(t, client.Call("GET", ts.URL, nil, &result))
+	require.Equal(t, 2, hits)
+}
+
+func TestHTTPClientCachePerCallHeaders(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		_ = json.NewEncoder(w).Encode(r.Header.Get("Authorization"))
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New()
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg, WithCache(time.Minute))
+	require.NoError(t, err)
+
+	var alice, bob, anonymous string
+	require.NoError(t, client.Call("GET", ts.URL, nil, &alice, WithHeader("Authorization", "Bearer alice")))
+	require.NoError(t, client.Call("GET", ts.URL, nil, &bob, WithHeader("Authorization", "Bearer bob")))
+	require.NoError(t, client.Call("GET", ts.URL, nil, &anonymous))
+	require.Equal(t, "Bearer alice", alice)
+	require.Equal(t, "Bearer bob", bob, "another caller's response must not be served")
+	require.Equal(t, "", anonymous)
+	require.Equal(t, 3, hits)
+}
+
+func TestHTTPClientCacheVary(t *testing.T) {
+	hits := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		if r.URL.Path == "/any" {
+			w.Header().Set("Vary", "*")
+		} else {
+			w.Header().Set("Vary", "Accept-Language")
+		}
+		_ = json.NewEncoder(w).Encode(r.Header.Get("Accept-Language"))
+	}))
+	defer ts.Close()
+
+	newClient := func(lang string) *HTTPClient {
+		cfg, err := config.New(config.WithDefault(map[string]interface{}{
+			"http_client_default_headers": map[string]string{"Accept-Language": lang},
+		}))
+		require.NoError(t, err)
+		client, err := NewHTTPClient(cfg, WithCache(time.Minute))
+		require.NoError(t, err)
+		return client
+	}
+	client := newClient("en")
+
+	var result string
+	require.NoError(t, client.Call("GET", ts.URL, nil, &result))
+	require.NoError(t, client.Call("GET", ts.URL, nil, &result))
+	require.Equal(t, "en", result)
+	require.Equal(t, 1, hits, "a request matching the Vary headers is served from cache")
+
+	// A request differing in a Vary header is not served the cached response
+	other := newClient("th")
+	other.cache = client.cache
+	require.NoError(t, other.Call("GET", ts.URL, nil, &result))
+	require.Equal(t, "th", result)
+	require.Equal(t, 2, hits)
+
+	// Vary: * is never cached
+	require.NoError(t, client.Call("GET", ts.URL+"/any", nil, &result))
+	require.NoError(t, client.Call("GET", ts.URL+"/any", nil, &result))
+	require.Equal(t, 4, hits)
+}
+
+func TestResponseCacheDropsStaleEntriesWithoutETag(t *testing.T) {
+	c := newResponseCache(time.Millisecond)
+	c.store("http://example.com/a", http.Header{}, []byte(`"a"`), http.Header{})
+	c.store("http://example.com/b", http.Header{}, []byte(`"b"`), http.Header{"Etag": {`"v1"`}})
+	// A no-cache response without an ETag is never stored
+	c.store("http://example.com/c", http.Header{}, []byte(`"c"`), http.Header{"Cache-Control": {"no-cache"}})
+	require.Len(t, c.entries, 2)
+
+	time.Sleep(5 * time.Millisecond)
+	e, fresh := c.get("http://example.com/a", http.Header{})
+	require.Nil(t, e)
+	require.False(t, fresh)
+	require.NotContains(t, c.entries, "http://example.com/a")
+
+	// Storing sweeps out other stale entries that cannot be revalidated
+	c.store("http://example.com/d", http.Header{}, []byte(`"d"`), http.Header{})
+	time.Sleep(5 * time.Millisecond)
+	c.store("http://example.com/e", http.Header{}, []byte(`"e"`), http.Header{})
+	require.NotContains(t, c.entries, "http://example.com/d")
+	require.Contains(t, c.entries, "http://example.com/b", "stale entries with an ETag are kept for revalidation")
+}
+
+func TestHTTPClientCallStream(t *testing.T) {
+	const chunkSize = 64 * 1024
+	const chunks = 16
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := bytes.Repeat([]byte("x"), chunkSize)
+		_, _ = w.Write(chunk)
+		w.(http.Flusher).Flush()
+		// Hold the rest of the body until the client has read the first chunk
+		<-release
+		for i := 1; i < chunks; i++ {
+			_, _ = w.Write(chunk)
+		}
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New()
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	body, err := client.CallStream(context.Background(), "GET", ts.URL, nil)
+	require.NoError(t, err)
+	defer body.Close()
+
+	first := make([]byte, chunkSize)
+	_, err = io.ReadFull(body, first)
+	require.NoError(t, err, "first chunk should be readable before the server sends the rest")
+	close(release)
+
+	rest, err := io.Copy(io.Discard, body)
+	require.NoError(t, err)
+	require.Equal(t, int64((chunks-1)*chunkSize), rest)
+}
+
+func TestHTTPClientCallStreamOutlastsTimeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("first,"))
+		w.(http.Flusher).Flush()
+		// The rest of the body arrives after the client timeout has passed
+		time.Sleep(300 * time.Millisecond)
+		_, _ = w.Write([]byte("last"))
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"http_client_timeout_ms": 100,
+	}))
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	body, err := client.CallStream(context.Background(), "GET", ts.URL, nil)
+	require.NoError(t, err)
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	require.Equal(t, "first,last", string(data))
+
+	// The timeout still bounds the wait for the response headers
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+	}))
+	defer slow.Close()
+	cfg, err = config.New(config.WithDefault(map[string]interface{}{
+		"http_client_timeout_ms":  100,
+		"http_client_max_retries": 0,
+	}))
+	require.NoError(t, err)
+	client, err = NewHTTPClient(cfg)
+	require.NoError(t, err)
+	_, err = client.CallStream(context.Background(), "GET", slow.URL, nil)
+	require.ErrorIs(t, err, ErrTimeout)
+}
+
+func TestHTTPClientCallStreamError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"error":"missing file"}`))
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New()
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	body, err := client.CallStream(context.Background(), "GET", ts.URL, nil)
+	require.Nil(t, body)
+	require.EqualError(t, err, "request failed with status 404: missing file")
+}
+
+func TestHTTPClientCallForm(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"grant_type": r.PostForm.Get("grant_type"),
+			"scope":      r.PostForm.Get("scope"),
+		})
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New()
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	values := url.Values{}
+	values.Set("grant_type", "client_credentials")
+	values.Set("scope", "read write")
+
+	var echoed map[string]string
+	err = client.CallForm(context.Background(), "POST", ts.URL, values, &echoed)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"grant_type": "client_credentials", "scope": "read write"}, echoed)
+}
+
+func TestHTTPClientUnexpectedContentType(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "application/json", r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte("service maintenance until 10:00"))
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New()
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	var out map[string]interface{}
+	err = client.Call("GET", ts.URL, nil, &out)
+	var ctErr *UnexpectedContentTypeError
+	require.ErrorAs(t, err, &ctErr)
+	require.Equal(t, "text/plain; charset=utf-8", ctErr.ContentType)
+	require.Equal(t, "service maintenance until 10:00", ctErr.Snippet)
+	require.EqualError(t, err, `unexpected response content type "text/plain; charset=utf-8" (expected JSON): service maintenance until 10:00`)
+}
+
+func TestHTTPClientDo(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		require.Equal(t, "PURGE", r.Method)
+		require.Equal(t, "text/plain", r.Header.Get("Content-Type"))
+		require.Equal(t, "go-core", r.Header.Get("X-Client"))
+		require.NotEmpty(t, r.Header.Get(RequestIDHeader))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, "cache-key", string(body))
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte("purged"))
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"http_client_disable_backoff": true,
+		"http_client_default_headers": map[string]string{"X-Client": "go-core", "Content-Type": "application/json"},
+	}))
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest("PURGE", ts.URL, io.NopCloser(bytes.NewBufferString("cache-key")))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "text/plain")
+
+	resp, err := client.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusAccepted, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "purged", string(body))
+	require.Equal(t, 2, attempts)
+}
+
+func TestHTTPClientIdempotencyKey(t *testing.T) {
+	var keys []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(IdempotencyKeyHeader))
+		if len(keys)%3 != 0 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"created"}`))
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"http_client_disable_backoff":  true,
+		"http_client_idempotency_keys": true,
+	}))
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	var out map[string]string
+	require.NoError(t, client.Call("POST", ts.URL, map[string]string{"item": "book"}, &out))
+	require.Len(t, keys, 3)
+	require.NotEmpty(t, keys[0])
+	require.Equal(t, keys[0], keys[1])
+	require.Equal(t, keys[0], keys[2])
+
+	// Each logical call gets its own key
+	require.NoError(t, client.Call("POST", ts.URL, map[string]string{"item": "pen"}, &out))
+	require.Len(t, keys, 6)
+	require.NotEqual(t, keys[0], keys[3])
+	require.Equal(t, keys[3], keys[5])
+
+	// A caller-supplied key is kept
+	require.NoError(t, client.Call("POST", ts.URL, nil, &out, WithHeader(IdempotencyKeyHeader, "order-42")))
+	require.Equal(t, []string{"order-42", "order-42", "order-42"}, keys[6:])
+
+	// Disabled by default
+	keys = nil
+	cfg, err = config.New(config.WithDefault(map[string]interface{}{"http_client_disable_backoff": true}))
+	require.NoError(t, err)
+	client, err = NewHTTPClient(cfg)
+	require.NoError(t, err)
+	require.NoError(t, client.Call("POST", ts.URL, nil, &out))
+	require.Equal(t, []string{"", "", ""}, keys)
+}
+
+func TestHTTPClientMaxRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/middle", http.StatusFound)
+	})
+	mux.HandleFunc("/middle", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/end", http.StatusFound)
+	})
+	mux.HandleFunc("/end", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"done"}`))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	newClient := func(settings map[string]interface{}) *HTTPClient {
+		cfg, err := config.New(config.WithDefault(settings))
+		require.NoError(t, err)
+		client, err := NewHTTPClient(cfg)
+		require.NoError(t, err)
+		return client
+	}
+	get := func(client *HTTPClient) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, ts.URL+"/start", nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	// Redirects disabled: the first 302 is surfaced with its Location
+	noRedirects := newClient(map[string]interface{}{"http_client_max_redirects": 0})
+	resp := get(noRedirects)
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	require.Equal(t, "/middle", resp.Header.Get("Location"))
+	var out map[string]string
+	err := noRedirects.Call("GET", ts.URL+"/start", nil, &out)
+	require.ErrorContains(t, err, "request failed with status 302")
+
+	// A limit of 1 stops at the second redirect
+	resp = get(newClient(map[string]interface{}{"http_client_max_redirects": 1}))
+	require.Equal(t, http.StatusFound, resp.StatusCode)
+	require.Equal(t, "/end", resp.Header.Get("Location"))
+
+	// The default follows the whole chain
+	client := newClient(map[string]interface{}{})
+	require.Equal(t, http.StatusOK, get(client).StatusCode)
+	require.NoError(t, client.Call("GET", ts.URL+"/start", nil, &out))
+	require.Equal(t, "done", out["status"])
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{"http_client_max_redirects": -1}))
+	require.NoError(t, err)
+	_, err = NewHTTPClient(cfg)
+	require.ErrorContains(t, err, "MaxRedirects")
+}
+
+func TestHTTPClientOnComplete(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"status":"ok"}`))
+	}))
+	defer ts.Close()
+
+	type completion struct {
+		method, url string
+		status      int
+		dur         time.Duration
+		err         error
+	}
+	var got []completion
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{"http_client_max_retries": 0}))
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg, WithOnComplete(func(method, url string, status int, dur time.Duration, err error) {
+		got = append(got, completion{method, url, status, dur, err})
+	}))
+	require.NoError(t, err)
+
+	var out map[string]string
+	require.NoError(t, client.Call("GET", ts.URL+"/ok", nil, &out))
+	require.Error(t, client.Call("POST", ts.URL+"/missing", map[string]string{"a": "b"}, nil))
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	require.Error(t, client.Call("GET", closed.URL, nil, nil))
+
+	require.Len(t, got, 3)
+	require.Equal(t, completion{method: "GET", url: ts.URL + "/ok", status: http.StatusOK, dur: got[0].dur}, got[0])
+	require.GreaterOrEqual(t, got[0].dur, 5*time.Millisecond)
+	require.Equal(t, "POST", got[1].method)
+	require.Equal(t, http.StatusNotFound, got[1].status)
+	require.Positive(t, got[1].dur)
+	require.NoError(t, got[1].err)
+	require.Equal(t, 0, got[2].status)
+	require.Error(t, got[2].err)
+	require.Positive(t, got[2].dur)
+}
+
+func TestHTTPClientTypedErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(300 * time.Millisecond)
+			w.WriteHeader(http.StatusOK)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error":"database unavailable"}`))
+		}
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"http_client_timeout_ms":      100,
+		"http_client_max_retries":     0,
+		"http_client_disable_backoff": true,
+	}))
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	err = client.Call("GET", ts.URL+"/fail", nil, nil)
+	var statusErr *HTTPStatusError
+	require.True(t, errors.As(err, &statusErr))
+	require.Equal(t, http.StatusInternalServerError, statusErr.Code)
+	require.Equal(t, "database unavailable", statusErr.Message)
+	require.JSONEq(t, `{"error":"database unavailable"}`, string(statusErr.Body))
+	require.EqualError(t, err, "request failed with status 500: database unavailable")
+
+	err = client.Call("GET", ts.URL+"/slow", nil, nil)
+	require.ErrorIs(t, err, ErrTimeout)
+	require.NotErrorIs(t, err, ErrNetwork)
+	var netErr net.Error
+	require.True(t, errors.As(err, &netErr))
+	require.True(t, netErr.Timeout())
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+	err = client.Call("GET", closed.URL, nil, nil)
+	require.ErrorIs(t, err, ErrNetwork)
+	require.NotErrorIs(t, err, ErrTimeout)
+
+	err = client.Call("INVALID", ts.URL, nil, nil)
+	require.ErrorIs(t, err, ErrValidation)
+	err = client.Call("POST", ts.URL, map[string]interface{}{"fn": func() {}}, nil)
+	require.ErrorIs(t, err, ErrValidation)
+}
+
+func TestHTTPClientStrictJSON(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"name":"alice","role":"admin"}`))
+	}))
+	defer ts.Close()
+
+	type user struct {
+		Name string `json:"name"`
+	}
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{"http_client_max_retries": 0}))
+	require.NoError(t, err)
+
+	lax, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+	var out user
+	require.NoError(t, lax.Call("GET", ts.URL, nil, &out))
+	require.Equal(t, "alice", out.Name)
+
+	strict, err := NewHTTPClient(cfg, WithStrictJSON())
+	require.NoError(t, err)
+	err = strict.Call("GET", ts.URL, nil, &user{})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `unknown field "role"`)
+
+	var all map[string]string
+	require.NoError(t, strict.Call("GET", ts.URL, nil, &all))
+	require.Equal(t, "admin", all["role"])
+}
+
+func TestHTTPClientRetryBudget(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	// Backoff grows 100ms, 200ms, ...; the second backoff would end after
+	// the 250ms budget, so only two of the six attempts are made.
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"http_client_max_retries":     5,
+		"http_client_backoff_base_ms": 100,
+		"http_client_retry_budget_ms": 250,
+	}))
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	start := time.Now()
+	err = client.Call("GET", ts.URL, nil, nil)
+	elapsed := time.Since(start)
+
+	var statusErr *HTTPStatusError
+	require.ErrorAs(t, err, &statusErr)
+	require.Equal(t, http.StatusServiceUnavailable, statusErr.Code)
+	require.Equal(t, 2, attempts)
+	require.Less(t, elapsed, 250*time.Millisecond)
+
+	cfg, err = config.New(config.WithDefault(map[string]interface{}{"http_client_retry_budget_ms": -1}))
+	require.NoError(t, err)
+	_, err = NewHTTPClient(cfg)
+	require.Error(t, err)
+}
+
+func TestHTTPClientCallAll(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"path":"` + r.URL.Path + `"}`))
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"http_client_max_retries":   0,
+		"http_client_batch_workers": 3,
+	}))
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	var requests []Request
+	for _, p := range []string{"/a", "/b", "/missing", "/c", "/d", "/e"} {
+		requests = append(requests, Request{Method: "GET", URL: ts.URL + p, Output: &map[string]string{}})
+	}
+	start := time.Now()
+	results := client.CallAll(context.Background(), requests)
+	elapsed := time.Since(start)
+
+	require.Len(t, results, 6)
+	for i, p := range []string{"/a", "/b", "/missing", "/c", "/d", "/e"} {
+		if p == "/missing" {
+			var statusErr *HTTPStatusError
+			require.ErrorAs(t, results[i].Err, &statusErr)
+			require.Equal(t, http.StatusNotFound, statusErr.Code)
+			continue
+		}
+		require.NoError(t, results[i].Err)
+		require.Equal(t, map[string]string{"path": p}, *results[i].Output.(*map[string]string))
+	}
+	require.Equal(t, 3, maxInFlight)
+	require.Less(t, elapsed, 250*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	results = client.CallAll(ctx, requests[:2])
+	for _, r := range results {
+		require.ErrorIs(t, r.Err, context.Canceled)
+	}
+	require.Empty(t, client.CallAll(context.Background(), nil))
+}
+
+func TestHTTPClientTimeoutDuration(t *testing.T) {
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"http_client_timeout_ms": 3000,
+		"http_client_timeout":    "1500ms",
+	}))
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+	require.Equal(t, 1500*time.Millisecond, client.client.Timeout)
+	require.Equal(t, 1500, client.config.TimeoutMs)
+
+	// Without a duration the millisecond setting still applies
+	cfg, err = config.New(config.WithDefault(map[string]interface{}{
+		"http_client_timeout_ms": 2000,
+	}))
+	require.NoError(t, err)
+	client, err = NewHTTPClient(cfg)
+	require.NoError(t, err)
+	require.Equal(t, 2*time.Second, client.client.Timeout)
+
+	// Durations are validated against the same range
+	cfg, err = config.New(config.WithDefault(map[string]interface{}{
+		"http_client_timeout": "1m",
+	}))
+	require.NoError(t, err)
+	_, err = NewHTTPClient(cfg)
+	require.Error(t, err)
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Proxied requests carry the absolute target URL
+		proxied = append(proxied, r.URL.String())
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`"via proxy"`))
+	}))
+	defer proxy.Close()
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"http_client_proxy_url":   proxy.URL,
+		"http_client_max_retries": 0,
+	}))
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	var out string
+	require.NoError(t, client.Call("GET", "http://api.example.invalid/items?page=2", nil, &out))
+	require.Equal(t, "via proxy", out)
+	require.Equal(t, []string{"http://api.example.invalid/items?page=2"}, proxied)
+
+	for _, bad := range []string{"proxy.internal:3128", "://bad"} {
+		cfg, err = config.New(config.WithDefault(map[string]interface{}{"http_client_proxy_url": bad}))
+		require.NoError(t, err)
+		_, err = NewHTTPClient(cfg)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestHTTPClientGETOmitsBody(t *testing.T) {
+	type seen struct {
+		method, contentType, body string
+	}
+	var requests []seen
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		requests = append(requests, seen{r.Method, r.Header.Get("Content-Type"), string(b)})
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`"ok"`))
+	}))
+	defer ts.Close()
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{"http_client_max_retries": 0}))
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	input := map[string]string{"q": "go"}
+	var out string
+	require.NoError(t, client.Call("GET", ts.URL, input, &out))
+	require.NoError(t, client.Call("HEAD", ts.URL, input, nil))
+	require.NoError(t, client.Call("GET", ts.URL, input, &out, WithRequestBody()))
+	require.NoError(t, client.Call("POST", ts.URL, input, &out))
+	for _, opts := range [][]CallOption{nil, {WithRequestBody()}} {
+		body, err := client.CallStream(context.Background(), "GET", ts.URL, input, opts...)
+		require.NoError(t, err)
+		require.NoError(t, body.Close())
+	}
+
+	require.Equal(t, []seen{
+		{http.MethodGet, "", ""},
+		{http.MethodHead, "", ""},
+		{http.MethodGet, "application/json", `{"q":"go"}`},
+		{http.MethodPost, "application/json", `{"q":"go"}`},
+		{http.MethodGet, "", ""},
+		{http.MethodGet, "application/json", `{"q":"go"}`},
+	}, requests)
+}
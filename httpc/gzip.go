@@ -0,0 +1,128 @@
+package httpc
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultGzipMinBytes is the response size below which gzipMiddleware sends
+// the body uncompressed unless server_gzip_min_bytes overrides it.
+const defaultGzipMinBytes = 1024
+
+// compressedContentTypes lists content type prefixes that are already
+// compressed or must be streamed, so gzipping them is skipped.
+var compressedContentTypes = []string{
+	"image/png", "image/jpeg", "image/gif", "image/webp", "image/avif",
+	"video/", "audio/", "font/woff",
+	"application/zip", "application/gzip", "application/x-gzip",
+	"application/x-bzip2", "application/x-7z-compressed", "application/zstd",
+	"text/event-stream",
+}
+
+// gzipMiddleware compresses responses for clients that accept gzip. The
+// body is buffered until it reaches minBytes; smaller responses, responses
+// that already carry a Content-Encoding and responses with a compressed
+// content type are sent as is.
+func gzipMiddleware(minBytes int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+		c.Writer.Header().Add("Vary", "Accept-Encoding")
+		gw := &gzipWriter{ResponseWriter: c.Writer, minBytes: minBytes}
+		c.Writer = gw
+		defer gw.finish()
+		c.Next()
+	}
+}
+
+// gzipWriter buffers the start of a response to decide whether to compress it.
+type gzipWriter struct {
+	gin.ResponseWriter
+	minBytes int
+	buf      []byte
+	decided  bool
+	gz       *gzip.Writer
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if !w.decided {
+		w.buf = append(w.buf, b...)
+		if len(w.buf) < w.minBytes {
+			return len(b), nil
+		}
+		if err := w.start(shouldCompress(w.Header())); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *gzipWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush sends buffered data immediately. A response flushed before reaching
+// the threshold is sent uncompressed.
+func (w *gzipWriter) Flush() {
+	if !w.decided {
+		_ = w.start(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// start settles whether the response is compressed and writes the buffer.
+func (w *gzipWriter) start(compress bool) error {
+	w.decided = true
+	buf := w.buf
+	w.buf = nil
+	if compress {
+		h := w.Header()
+		h.Set("Content-Encoding", "gzip")
+		h.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+		_, err := w.gz.Write(buf)
+		return err
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+// finish writes a response that stayed below the threshold and terminates
+// the gzip stream.
+func (w *gzipWriter) finish() {
+	if !w.decided {
+		_ = w.start(false)
+	}
+	if w.gz != nil {
+		_ = w.gz.Close()
+	}
+}
+
+// shouldCompress reports whether a response with header h is worth gzipping.
+func shouldCompress(h http.Header) bool {
+	if h.Get("Content-Encoding") != "" {
+		return false
+	}
+	ct := strings.ToLower(h.Get("Content-Type"))
+	for _, prefix := range compressedContentTypes {
+		if strings.HasPrefix(ct, prefix) {
+			return false
+		}
+	}
+	return true
+}
@@ -4,9 +4,13 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"net/url"
+	"path"
 	"reflect"
 	"strings"
 	"time"
@@ -19,18 +23,38 @@ import (
 )
 
 type ServerConfig struct {
-	OtelEnabled bool `json:"otel_enabled" default:"false"`
-	Port        int  `json:"port" default:"8080" required:"true" validate:"gt=0,lte=65535"`
+	OtelEnabled      bool `json:"otel_enabled" default:"false"`
+	Port             int  `json:"port" default:"8080" required:"true" validate:"gt=0,lte=65535"`
+	RequestTimeoutMs int  `json:"server_request_timeout_ms" default:"0" validate:"gte=0"`
+	MaxConnections   int  `json:"server_max_connections" default:"0" validate:"gte=0"`
+	GzipEnabled      bool `json:"server_gzip_enabled" default:"false"`
+	GzipMinBytes     int  `json:"server_gzip_min_bytes" default:"1024" validate:"gte=0"`
+	// TrustedProxies lists the proxy IPs or CIDRs whose X-Forwarded-For and
+	// X-Real-IP headers are used for the client IP. Empty keeps gin's default
+	// of trusting every proxy.
+	TrustedProxies []string `json:"server_trusted_proxies"`
 }
 
 type ClientConfig struct {
-	OtelEnabled    bool  `json:"otel_enabled" default:"false"`
-	TimeoutMs      int   `json:"http_client_timeout_ms" default:"3000" required:"true" validate:"gte=100,lte=30000"`
-	MaxRetries     int   `json:"http_client_max_retries" default:"3" required:"true" validate:"gte=0,lte=5"`
-	BackoffBaseMs  int64 `json:"http_client_backoff_base_ms" default:"100" validate:"gte=50,lte=1000"`
-	BackoffMaxMs   int64 `json:"http_client_backoff_max_ms" default:"1000" validate:"gte=100,lte=5000"`
-	BackoffFactor  int   `json:"http_client_backoff_factor" default:"2" validate:"gte=1,lte=5"`
-	DisableBackoff bool  `json:"http_client_disable_backoff" default:"false"`
+	OtelEnabled      bool              `json:"otel_enabled" default:"false"`
+	TimeoutMs        int               `json:"http_client_timeout_ms" default:"3000" required:"true" validate:"gte=100,lte=30000"`
+	Timeout          time.Duration     `json:"http_client_timeout"` // Overrides TimeoutMs when set, e.g. "1500ms" or "2s"
+	MaxRetries       int               `json:"http_client_max_retries" default:"3" required:"true" validate:"gte=0,lte=5"`
+	BackoffBaseMs    int64             `json:"http_client_backoff_base_ms" default:"100" validate:"gte=50,lte=1000"`
+	BackoffMaxMs     int64             `json:"http_client_backoff_max_ms" default:"1000" validate:"gte=100,lte=5000"`
+	BackoffFactor    int               `json:"http_client_backoff_factor" default:"2" validate:"gte=1,lte=5"`
+	DisableBackoff   bool              `json:"http_client_disable_backoff" default:"false"`
+	DefaultHeaders   map[string]string `json:"http_client_default_headers"`
+	BreakerThreshold int               `json:"http_client_breaker_threshold" default:"0" validate:"gte=0"`
+	BreakerResetMs   int               `json:"http_client_breaker_reset_ms" default:"30000" validate:"gte=1"`
+	TLSCertFile      string            `json:"http_client_tls_cert_file" default:""`
+	TLSKeyFile       string            `json:"http_client_tls_key_file" default:""`
+	CAFile           string            `json:"http_client_ca_file" default:""`
+	IdempotencyKeys  bool              `json:"http_client_idempotency_keys" default:"false"`
+	MaxRedirects     int               `json:"http_client_max_redirects" default:"10" validate:"gte=0"`
+	RetryBudgetMs    int               `json:"http_client_retry_budget_ms" default:"0" validate:"gte=0"`
+	BatchWorkers     int               `json:"http_client_batch_workers" default:"10" validate:"gte=1"`
+	ProxyURL         string            `json:"http_client_proxy_url" default:""`
 }
 
 type Server struct {
@@ -39,22 +63,64 @@ type Server struct {
 	otelEnabled bool
 	config      *config.Config
 	server      *http.Server
+	onShutdown  []func(context.Context) error
+	routes      []RouteInfo
+	streams     map[string]bool // long-lived routes exempt from the request timeout
 }
 
 type HTTPClient struct {
 	client      *http.Client
+	stream      *http.Client // no overall timeout; bounds only the wait for response headers
 	config      ClientConfig
 	otelEnabled bool
+	breaker     *circuitBreaker
+	cache       *responseCache
+	onComplete  CompleteFunc
+	strictJSON  bool
 }
 
 func NewServer(c *config.Config) (*Server, error) {
 	logger.Info("Creating new server")
 	gin.SetMode(gin.DebugMode)
 	engine := gin.New()
+	if proxies := stringList(c.Get("server_trusted_proxies")); len(proxies) > 0 {
+		if err := engine.SetTrustedProxies(proxies); err != nil {
+			return nil, fmt.Errorf("invalid server_trusted_proxies: %w", err)
+		}
+		logger.Info("Using trusted proxies", logger.String("proxies", strings.Join(proxies, ",")))
+	}
 	engine.Use(gin.Recovery())
+	engine.Use(requestIDMiddleware())
+	maxConns := c.GetIntWithDefault("server_max_connections", 0)
+	if maxConns < 0 {
+		return nil, fmt.Errorf("invalid server_max_connections: %d", maxConns)
+	}
+	if maxConns > 0 {
+		logger.Info("Using server connection limit", logger.Int("max_connections", maxConns))
+		engine.Use(maxConnectionsMiddleware(maxConns))
+	}
+	timeoutMs := c.GetIntWithDefault("server_request_timeout_ms", 0)
+	if timeoutMs < 0 {
+		return nil, fmt.Errorf("invalid server_request_timeout_ms: %d", timeoutMs)
+	}
+	streams := map[string]bool{}
+	if timeoutMs > 0 {
+		logger.Info("Using server request timeout", logger.Int("timeout_ms", timeoutMs))
+		engine.Use(timeoutMiddleware(time.Duration(timeoutMs)*time.Millisecond, func(c *gin.Context) bool {
+			return streams[streamKey(c.Request.Method, c.FullPath())]
+		}))
+	}
+	gzipMinBytes := c.GetIntWithDefault("server_gzip_min_bytes", defaultGzipMinBytes)
+	if gzipMinBytes < 0 {
+		return nil, fmt.Errorf("invalid server_gzip_min_bytes: %d", gzipMinBytes)
+	}
+	if c.GetBoolWithDefault("server_gzip_enabled", false) {
+		logger.Info("Using gzip compression", logger.Int("min_bytes", gzipMinBytes))
+		engine.Use(gzipMiddleware(gzipMinBytes))
+	}
 
 	swaggerDoc := map[string]interface{}{
-		"openapi": "3.0.3",
+		"openapi": OpenAPIVersion30,
 		"info": map[string]interface{}{
 			"title":   "httpc API",
 			"version": "1.0.0",
@@ -66,8 +132,10 @@ func NewServer(c *config.Config) (*Server, error) {
 		swagger:     swaggerDoc,
 		otelEnabled: c.GetBool("otel_enabled"),
 		config:      c,
+		streams:     streams,
 	}
 
+	engine.NoRoute(notFoundHandler)
 	engine.GET("/health", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"status": "healthy"})
 	})
@@ -99,7 +167,7 @@ func NewServer(c *config.Config) (*Server, error) {
 }
 
 func (s *Server) ListenAndServe() error {
-	port := s.config.Get("port").(int)
+	port := s.config.GetIntWithDefault("port", 8080)
 	addr := fmt.Sprintf(":%d", port)
 	s.server = &http.Server{
 		Addr:    addr,
@@ -113,12 +181,68 @@ func (s *Server) ListenAndServe() error {
 	return nil
 }
 
+// Shutdown gracefully stops the server, then runs the OnShutdown hooks in
+// registration order. Every hook runs even if the server or an earlier hook
+// fails; the errors are joined.
 func (s *Server) Shutdown(ctx context.Context) error {
-	if s.server == nil {
-		return nil
+	var errs []error
+	if s.server != nil {
+		logger.Info("Shutting down server")
+		if err := s.server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("shutdown server: %w", err))
+		}
+	}
+	for i, hook := range s.onShutdown {
+		if err := hook(ctx); err != nil {
+			logger.Error("Shutdown hook failed", logger.Int("hook", i), logger.ErrField(err))
+			errs = append(errs, fmt.Errorf("shutdown hook %d: %w", i, err))
+		}
 	}
-	logger.Info("Shutting down server")
-	return s.server.Shutdown(ctx)
+	return errors.Join(errs...)
+}
+
+// OnShutdown registers hook to run during Shutdown after the server has
+// stopped accepting requests, for cleanup such as closing broker
+// connections or flushing the logger. hook receives Shutdown's context.
+func (s *Server) OnShutdown(hook func(ctx context.Context) error) {
+	s.onShutdown = append(s.onShutdown, hook)
+}
+
+// SetNotFoundHandler replaces the handler used for requests that match no
+// registered route. By default such requests get a JSON 404
+// {"error":"not found"}.
+func (s *Server) SetNotFoundHandler(handler gin.HandlerFunc) {
+	s.engine.NoRoute(handler)
+}
+
+// stringList reads a list setting given either as a list, such as from a YAML
+// file, or as a comma-separated string, such as from an environment variable.
+// Blank entries are dropped.
+func stringList(value interface{}) []string {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = strings.Split(v, ",")
+	case []string:
+		items = v
+	case []interface{}:
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+	}
+	var out []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// notFoundHandler answers unknown paths with the same JSON error shape used
+// by the registered endpoints instead of gin's plain-text 404.
+func notFoundHandler(c *gin.Context) {
+	c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
 }
 
 func (s *Server) RegisterService(svc interface{}, opts ...ServiceOption) error {
@@ -142,16 +266,28 @@ func (s *Server) RegisterService(svc interface{}, opts ...ServiceOption) error {
 
 func (s *Server) registerMethods(methods []MethodInfo, cfg *serviceConfig, svc interface{}) error {
 	for _, m := range methods {
-		path := fmt.Sprintf("%s/%s", cfg.prefix, m.Name)
+		path := fmt.Sprintf("%s/%s", cfg.prefix, methodPath(m))
 		method := strings.ToUpper(m.HTTPMethod)
 		if !isValidHTTPMethod(method) {
 			logger.Warn("Skipping invalid HTTP method", logger.String("method", m.HTTPMethod))
 			continue
 		}
-		s.engine.Handle(method, path, s.handleMethod(m))
+		mw := cfg.middleware[m.Name]
+		if isStreamOutput(m.OutputType) {
+			s.markStream(method, path)
+		}
+		s.engine.Handle(method, path, append(mw[:len(mw):len(mw)], s.handleMethod(m))...)
+		// gin joins route paths with path.Join, so record the cleaned form
+		s.routes = append(s.routes, RouteInfo{Method: method, Path: cleanRoute(path), OperationID: m.Name})
 		logger.Info("Registered endpoint", logger.String("method", m.HTTPMethod), logger.String("path", path))
 	}
 
+	for name := range cfg.middleware {
+		if !hasMethod(methods, name) {
+			logger.Warn("Middleware registered for unknown method", logger.String("method", name))
+		}
+	}
+
 	if len(methods) > 0 {
 		if err := updateSwaggerDoc(s, svc, cfg.prefix); err != nil {
 			logger.Error("Failed to update Swagger doc", logger.ErrField(err))
@@ -163,6 +299,44 @@ func (s *Server) registerMethods(methods []MethodInfo, cfg *serviceConfig, svc i
 	return nil
 }
 
+// Routes returns the endpoints registered by RegisterService, in
+// registration order. Built-in endpoints such as /health and the Swagger
+// docs, streams and WebSocket endpoints are not included.
+func (s *Server) Routes() []RouteInfo {
+	return append([]RouteInfo(nil), s.routes...)
+}
+
+// cleanRoute returns route the way gin stores it: cleaned with path.Clean,
+// keeping a trailing slash.
+func cleanRoute(route string) string {
+	cleaned := path.Clean(route)
+	if strings.HasSuffix(route, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+	return cleaned
+}
+
+// markStream exempts a long-lived route, such as an event stream or a
+// WebSocket, from server_request_timeout_ms.
+func (s *Server) markStream(method, route string) {
+	s.streams[streamKey(method, cleanRoute(route))] = true
+}
+
+// streamKey identifies a route in Server.streams.
+func streamKey(method, route string) string {
+	return method + " " + route
+}
+
+// hasMethod reports whether methods contains a method with the given name.
+func hasMethod(methods []MethodInfo, name string) bool {
+	for _, m := range methods {
+		if m.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Server) handleMethod(m MethodInfo) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Placeholder: no-op for tracing
@@ -175,9 +349,25 @@ func (s *Server) handleMethod(m MethodInfo) gin.HandlerFunc {
 		}()
 
 		reqCtx := ctx
+		reqIDField := logger.String("request_id", RequestIDFromContext(reqCtx))
+		if contentType := unsupportedContentType(c, m); contentType != "" {
+			logger.ErrorContext(reqCtx, "Unsupported content type", reqIDField, logger.String("content_type", contentType))
+			c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": fmt.Sprintf("unsupported content type %q, expected application/json", contentType)})
+			return
+		}
+
 		var inputVal interface{}
 		inputType := m.InputType
-		if inputType.Kind() == reflect.String {
+		if isRawBody(m) {
+			// Pass the body through untouched, e.g. for webhook payloads
+			body, err := io.ReadAll(c.Request.Body)
+			if err != nil {
+				logger.ErrorContext(reqCtx, "Reading request body failed", reqIDField, logger.ErrField(err))
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			inputVal = reflect.ValueOf(body).Convert(inputType).Interface()
+		} else if inputType.Kind() == reflect.String {
 			// For string inputs, use query parameter directly
 			if m.HTTPMethod == http.MethodGet || m.HTTPMethod == http.MethodHead {
 				query := c.Query("name")
@@ -185,30 +375,22 @@ func (s *Server) handleMethod(m MethodInfo) gin.HandlerFunc {
 			} else {
 				inputVal = reflect.New(inputType).Interface()
 				if err := c.ShouldBindJSON(inputVal); err != nil {
-					logger.ErrorContext(reqCtx, "JSON binding failed", logger.ErrField(err))
+					logger.ErrorContext(reqCtx, "JSON binding failed", reqIDField, logger.ErrField(err))
 					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 					return
 				}
 			}
 		} else {
-			// For struct inputs, bind and validate
+			// For struct inputs, bind path, query and body, then validate once
 			inputVal = reflect.New(inputType).Interface()
-			if m.HTTPMethod == http.MethodGet {
-				if err := c.ShouldBindQuery(inputVal); err != nil {
-					logger.ErrorContext(reqCtx, "Query binding failed", logger.ErrField(err))
-					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-					return
-				}
-			} else {
-				if err := c.ShouldBindJSON(inputVal); err != nil {
-					logger.ErrorContext(reqCtx, "JSON binding failed", logger.ErrField(err))
-					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-					return
-				}
+			if source, err := bindInput(c, strings.ToUpper(m.HTTPMethod), inputVal); err != nil {
+				logger.ErrorContext(reqCtx, source+" binding failed", reqIDField, logger.ErrField(err))
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
 			}
 			validate := validator.New()
 			if err := validate.Struct(inputVal); err != nil {
-				logger.ErrorContext(reqCtx, "Validation failed", logger.ErrField(err))
+				logger.ErrorContext(reqCtx, "Validation failed", reqIDField, logger.ErrField(err))
 				c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("validation failed: %s", err.Error())})
 				return
 			}
@@ -216,7 +398,7 @@ func (s *Server) handleMethod(m MethodInfo) gin.HandlerFunc {
 
 		// Prepare input for method call
 		var callInput reflect.Value
-		if inputType.Kind() == reflect.String {
+		if isRawBody(m) || inputType.Kind() == reflect.String {
 			callInput = reflect.ValueOf(inputVal)
 		} else {
 			callInput = reflect.ValueOf(inputVal).Elem()
@@ -226,49 +408,64 @@ func (s *Server) handleMethod(m MethodInfo) gin.HandlerFunc {
 		results := m.Func.Call([]reflect.Value{callInput})
 		if !results[1].IsNil() {
 			err := results[1].Interface().(error)
-			logger.ErrorContext(reqCtx, "Method execution failed", logger.ErrField(err))
-			logger.InfoContext(reqCtx, "Sending error response", logger.String("body", fmt.Sprintf(`{"error":"%s"}`, err.Error())))
+			logger.ErrorContext(reqCtx, "Method execution failed", reqIDField, logger.ErrField(err))
+			logger.InfoContext(reqCtx, "Sending error response", reqIDField, logger.String("body", fmt.Sprintf(`{"error":"%s"}`, err.Error())))
 			c.Data(http.StatusInternalServerError, "application/json", []byte(`{"error":"`+err.Error()+`"}`))
-			logger.InfoContext(reqCtx, "After Data write", logger.Int("status", c.Writer.Status()), logger.Any("headers", c.Writer.Header()))
+			logger.InfoContext(reqCtx, "After Data write", reqIDField, logger.Int("status", c.Writer.Status()), logger.Any("headers", c.Writer.Header()))
 			return
 		}
 		if strings.ToUpper(m.HTTPMethod) == http.MethodHead {
 			c.Status(http.StatusOK)
 			return
 		}
+		if isStreamOutput(m.OutputType) {
+			streamResults(c, results[0], reqIDField)
+			return
+		}
 
 		c.JSON(http.StatusOK, results[0].Interface())
 	}
 }
 
-func getIntConfig(c *config.Config, key string, defaultValue int) int {
-	if val := c.Get(key); val != nil {
-		if intVal, ok := val.(int); ok {
-			return intVal
-		}
-	}
-	return defaultValue
-}
-
-func getBoolConfig(c *config.Config, key string, defaultValue bool) bool {
-	if val := c.Get(key); val != nil {
-		if boolVal, ok := val.(bool); ok {
-			return boolVal
+// requestIDMiddleware reads the X-Request-ID header, generating an id when it
+// is missing, and attaches it to the request context and the response header.
+func requestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" {
+			requestID = uuid.New().String()
 		}
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), requestID))
+		c.Header(RequestIDHeader, requestID)
+		c.Next()
 	}
-	return defaultValue
 }
 
-func NewHTTPClient(c *config.Config) (*HTTPClient, error) {
+func NewHTTPClient(c *config.Config, opts ...ClientOption) (*HTTPClient, error) {
 	logger.Info("Creating new HTTP client")
 	cfg := ClientConfig{
-		OtelEnabled:    getBoolConfig(c, "otel_enabled", false),
-		TimeoutMs:      getIntConfig(c, "http_client_timeout_ms", 3000),
-		MaxRetries:     getIntConfig(c, "http_client_max_retries", 3),
-		BackoffBaseMs:  int64(getIntConfig(c, "http_client_backoff_base_ms", 100)),
-		BackoffMaxMs:   int64(getIntConfig(c, "http_client_backoff_max_ms", 1000)),
-		BackoffFactor:  getIntConfig(c, "http_client_backoff_factor", 2),
-		DisableBackoff: getBoolConfig(c, "http_client_disable_backoff", false),
+		OtelEnabled:      c.GetBoolWithDefault("otel_enabled", false),
+		TimeoutMs:        c.GetIntWithDefault("http_client_timeout_ms", 3000),
+		Timeout:          c.GetDuration("http_client_timeout"),
+		MaxRetries:       c.GetIntWithDefault("http_client_max_retries", 3),
+		BackoffBaseMs:    int64(c.GetIntWithDefault("http_client_backoff_base_ms", 100)),
+		BackoffMaxMs:     int64(c.GetIntWithDefault("http_client_backoff_max_ms", 1000)),
+		BackoffFactor:    c.GetIntWithDefault("http_client_backoff_factor", 2),
+		DisableBackoff:   c.GetBoolWithDefault("http_client_disable_backoff", false),
+		DefaultHeaders:   c.GetStringMapString("http_client_default_headers"),
+		BreakerThreshold: c.GetIntWithDefault("http_client_breaker_threshold", 0),
+		BreakerResetMs:   c.GetIntWithDefault("http_client_breaker_reset_ms", 30000),
+		TLSCertFile:      c.GetStringWithDefault("http_client_tls_cert_file", ""),
+		TLSKeyFile:       c.GetStringWithDefault("http_client_tls_key_file", ""),
+		CAFile:           c.GetStringWithDefault("http_client_ca_file", ""),
+		IdempotencyKeys:  c.GetBoolWithDefault("http_client_idempotency_keys", false),
+		MaxRedirects:     c.GetIntWithDefault("http_client_max_redirects", 10),
+		RetryBudgetMs:    c.GetIntWithDefault("http_client_retry_budget_ms", 0),
+		BatchWorkers:     c.GetIntWithDefault("http_client_batch_workers", 10),
+		ProxyURL:         c.GetStringWithDefault("http_client_proxy_url", ""),
+	}
+	if cfg.Timeout > 0 {
+		cfg.TimeoutMs = int(cfg.Timeout.Milliseconds())
 	}
 
 	validate := validator.New()
@@ -278,115 +475,546 @@ func NewHTTPClient(c *config.Config) (*HTTPClient, error) {
 
 	logger.Info("Using HTTP client timeout", logger.Int("timeout_ms", cfg.TimeoutMs))
 	logger.Info("Using HTTP max retries", logger.Int("max_retries", cfg.MaxRetries))
+	if cfg.RetryBudgetMs > 0 {
+		logger.Info("Using HTTP retry budget", logger.Int("retry_budget_ms", cfg.RetryBudgetMs))
+	}
 
 	client := &http.Client{
-		Timeout: time.Duration(cfg.TimeoutMs) * time.Millisecond,
+		Timeout:       time.Duration(cfg.TimeoutMs) * time.Millisecond,
+		CheckRedirect: redirectPolicy(cfg.MaxRedirects),
+	}
+	tlsCfg, err := buildTLSConfig(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure TLS: %w", err)
+	}
+	proxy, err := proxyFunc(cfg.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid http_client_proxy_url: %w", err)
+	}
+	if tlsCfg != nil || cfg.ProxyURL != "" {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		if tlsCfg != nil {
+			logger.Info("Using HTTP client TLS", logger.Bool("client_cert", len(tlsCfg.Certificates) > 0), logger.Bool("custom_ca", tlsCfg.RootCAs != nil))
+			transport.TLSClientConfig = tlsCfg
+		}
+		transport.Proxy = proxy
+		client.Transport = transport
 	}
-	return &HTTPClient{
+	h := &HTTPClient{
 		client:      client,
+		stream:      newStreamClient(client),
 		config:      cfg,
 		otelEnabled: cfg.OtelEnabled,
-	}, nil
+	}
+	if cfg.BreakerThreshold > 0 {
+		logger.Info("Using HTTP circuit breaker", logger.Int("threshold", cfg.BreakerThreshold), logger.Int("reset_ms", cfg.BreakerResetMs))
+		h.breaker = newCircuitBreaker(cfg.BreakerThreshold, time.Duration(cfg.BreakerResetMs)*time.Millisecond)
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, nil
 }
 
-func (h *HTTPClient) Call(method, url string, input, output interface{}) error {
-	// Placeholder: no-op for tracing
-	ctx := context.Background()
-	var span interface{} // Placeholder
-	defer func() {
-		if span != nil {
-			// No-op
+// Call sends a request with a background context. See CallContext.
+func (h *HTTPClient) Call(method, url string, input, output interface{}, opts ...CallOption) error {
+	return h.CallContext(context.Background(), method, url, input, output, opts...)
+}
+
+// CallContext sends a request, retrying on transport errors and 5xx
+// responses. A non-nil input is sent as a JSON body, except on GET and HEAD
+// requests unless WithRequestBody is passed. Every attempt carries the same
+// X-Request-ID header: the request id already on ctx, or a newly generated
+// one. When the circuit breaker is enabled and open, it fails fast with
+// ErrCircuitOpen.
+func (h *HTTPClient) CallContext(ctx context.Context, method, url string, input, output interface{}, opts ...CallOption) error {
+	var bodyData []byte
+	if input != nil {
+		var err error
+		bodyData, err = json.Marshal(input)
+		if err != nil {
+			return fmt.Errorf("%w: failed to marshal input: %w", ErrValidation, err)
 		}
-	}()
+	}
+	return h.call(ctx, method, url, bodyData, "application/json", output, opts)
+}
+
+// CallForm sends values as an application/x-www-form-urlencoded body and
+// decodes the JSON response into output. Retries, headers and request ids
+// behave as in CallContext.
+func (h *HTTPClient) CallForm(ctx context.Context, method, url string, values url.Values, output interface{}, opts ...CallOption) error {
+	return h.call(ctx, method, url, []byte(values.Encode()), "application/x-www-form-urlencoded", output, opts)
+}
+
+// call sends bodyData with the given content type and decodes the response
+// into output. It implements CallContext and CallForm.
+func (h *HTTPClient) call(ctx context.Context, method, url string, bodyData []byte, contentType string, output interface{}, opts []CallOption) error {
+	callCfg := &callConfig{}
+	for _, opt := range opts {
+		opt(callCfg)
+	}
+
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = uuid.New().String()
+		ctx = WithRequestID(ctx, requestID)
+	}
+	reqIDField := logger.String("request_id", requestID)
 
-	reqCtx := ctx
 	method = strings.ToUpper(method)
 	if !isValidHTTPMethod(method) {
-		err := fmt.Errorf("invalid HTTP method: %s", method)
-		logger.ErrorContext(reqCtx, "Invalid HTTP method", logger.ErrField(err))
+		err := fmt.Errorf("%w: invalid HTTP method: %s", ErrValidation, method)
+		logger.ErrorContext(ctx, "Invalid HTTP method", reqIDField, logger.ErrField(err))
 		return err
 	}
 
-	var bodyData []byte
-	var err error
-	if input != nil {
-		bodyData, err = json.Marshal(input)
+	if bodyData != nil && !callCfg.sendsBody(method) {
+		logger.DebugContext(ctx, "Omitting request body", reqIDField, logger.String("method", method))
+		bodyData = nil
+	}
+	var body io.Reader
+	if bodyData != nil {
+		body = bytes.NewReader(bodyData)
+		logger.InfoContext(ctx, "Request body", reqIDField, logger.Int("length", len(bodyData)))
+	}
+	req, err := h.newRequest(ctx, method, url, body, contentType, requestID, callCfg)
+	if err != nil {
+		return err
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+
+	// Per-call headers may carry a caller's credentials, so responses to
+	// such calls are neither served from nor stored in the shared cache.
+	var cached *cacheEntry
+	useCache := h.cache != nil && method == http.MethodGet && len(callCfg.headers) == 0
+	if useCache {
+		var fresh bool
+		cached, fresh = h.cache.get(url, req.Header)
+		if fresh {
+			logger.InfoContext(ctx, "Serving cached response", reqIDField, logger.String("url", url))
+			return h.decodeOutput(cached.body, output)
+		}
+		if cached != nil && cached.etag != "" {
+			req.Header.Set("If-None-Match", cached.etag)
+		}
+	}
+
+	resp, err := h.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil && cached.etag != "" {
+		h.cache.refresh(url, resp.Header)
+		logger.InfoContext(ctx, "Cached response revalidated", reqIDField, logger.String("url", url))
+		return h.decodeOutput(cached.body, output)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return responseError(ctx, resp, reqIDField)
+	}
+	if output != nil || useCache {
+		bodyBytes, err := io.ReadAll(resp.Body)
+		if err != nil {
+			logger.ErrorContext(ctx, "Failed to read response body", reqIDField, logger.ErrField(err))
+			return fmt.Errorf("failed to read response body: %w", transportError(err))
+		}
+		if useCache {
+			h.cache.store(url, req.Header, bodyBytes, resp.Header)
+		}
+		if err := h.decodeOutput(bodyBytes, output); err != nil {
+			// Explain undecodable non-JSON responses by their content type
+			if ctErr := checkContentType(resp.Header.Get("Content-Type"), bodyBytes); ctErr != nil {
+				logger.ErrorContext(ctx, "Unexpected response content type", reqIDField, logger.ErrField(ctErr))
+				return ctErr
+			}
+			return err
+		}
+	}
+	logger.InfoContext(ctx, "Request completed successfully", reqIDField)
+	return nil
+}
+
+// CallStream sends a request like CallContext but returns the response body
+// unread so large downloads can be streamed. The caller must close it. As in
+// CallContext, GET and HEAD requests send no body unless WithRequestBody is
+// passed. Transport
+// errors and 5xx responses are retried before any body is returned; GET
+// caching does not apply. The client timeout bounds each attempt only until
+// the response headers arrive; reading the body is bounded by ctx alone.
+func (h *HTTPClient) CallStream(ctx context.Context, method, url string, input interface{}, opts ...CallOption) (io.ReadCloser, error) {
+	callCfg := &callConfig{}
+	for _, opt := range opts {
+		opt(callCfg)
+	}
+
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = uuid.New().String()
+		ctx = WithRequestID(ctx, requestID)
+	}
+	reqIDField := logger.String("request_id", requestID)
+
+	method = strings.ToUpper(method)
+	if !isValidHTTPMethod(method) {
+		err := fmt.Errorf("%w: invalid HTTP method: %s", ErrValidation, method)
+		logger.ErrorContext(ctx, "Invalid HTTP method", reqIDField, logger.ErrField(err))
+		return nil, err
+	}
+
+	var body io.Reader
+	if input != nil && !callCfg.sendsBody(method) {
+		logger.DebugContext(ctx, "Omitting request body", reqIDField, logger.String("method", method))
+	} else if input != nil {
+		bodyData, err := json.Marshal(input)
 		if err != nil {
-			return fmt.Errorf("failed to marshal input: %w", err)
+			return nil, fmt.Errorf("%w: failed to marshal input: %w", ErrValidation, err)
 		}
+		body = bytes.NewReader(bodyData)
+	}
+	req, err := h.newRequest(ctx, method, url, body, "application/json", requestID, callCfg)
+	if err != nil {
+		return nil, err
 	}
 
-	for attempt := 1; attempt <= h.config.MaxRetries+1; attempt++ {
-		var body io.Reader
-		if bodyData != nil {
-			body = bytes.NewReader(bodyData) // Fresh reader for each attempt
-			logger.InfoContext(reqCtx, "Request body", logger.Int("length", len(bodyData)), logger.Int("attempt", attempt))
+	resp, err := h.send(h.stream, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := responseError(ctx, resp, reqIDField)
+		resp.Body.Close()
+		return nil, err
+	}
+	logger.InfoContext(ctx, "Streaming response", reqIDField, logger.Int("status", resp.StatusCode))
+	return resp.Body, nil
+}
+
+// Do sends a caller-built request with the client's timeout, retries, circuit
+// breaker, default headers and request id, for requests that do not fit
+// Call's JSON handling. Headers already set on req take precedence over
+// default headers. Transport errors and 5xx responses are retried; the body
+// is buffered for resending unless req.GetBody is set. When idempotency keys
+// are enabled, POST and PATCH requests without an Idempotency-Key header get a
+// new key that every retry of the request reuses. The final response is
+// returned whatever its status and the caller must close its body. The
+// WithOnComplete callback, if any, is called once Do returns.
+func (h *HTTPClient) Do(req *http.Request) (*http.Response, error) {
+	return h.send(h.client, req)
+}
+
+// send implements Do, sending every attempt through client.
+func (h *HTTPClient) send(client *http.Client, req *http.Request) (*http.Response, error) {
+	if h.onComplete == nil {
+		return h.do(client, req)
+	}
+	start := time.Now()
+	resp, err := h.do(client, req)
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	h.onComplete(req.Method, req.URL.String(), status, time.Since(start), err)
+	return resp, err
+}
+
+// do implements send.
+func (h *HTTPClient) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	requestID := req.Header.Get(RequestIDHeader)
+	if requestID == "" {
+		requestID = RequestIDFromContext(ctx)
+	}
+	if requestID == "" {
+		requestID = uuid.New().String()
+	}
+	ctx = WithRequestID(ctx, requestID)
+	reqIDField := logger.String("request_id", requestID)
+
+	base := req.Clone(ctx)
+	base.Header.Set(RequestIDHeader, requestID)
+	for k, v := range h.config.DefaultHeaders {
+		if base.Header.Get(k) == "" {
+			base.Header.Set(k, v)
 		}
+	}
+	if h.config.IdempotencyKeys && (base.Method == http.MethodPost || base.Method == http.MethodPatch) && base.Header.Get(IdempotencyKeyHeader) == "" {
+		base.Header.Set(IdempotencyKeyHeader, uuid.New().String())
+	}
 
-		req, err := http.NewRequestWithContext(ctx, method, url, body)
+	getBody := req.GetBody
+	if getBody == nil && req.Body != nil && req.Body != http.NoBody {
+		bodyData, err := io.ReadAll(req.Body)
+		req.Body.Close()
 		if err != nil {
-			return fmt.Errorf("failed to create request: %w", err)
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		getBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(bodyData)), nil
+		}
+	}
+
+	// Only outcomes from the upstream count towards the breaker, not local
+	// errors or the caller canceling the request.
+	upstreamReached, upstreamFailed := false, false
+	if h.breaker != nil {
+		if !h.breaker.allow() {
+			logger.WarnContext(ctx, "Circuit breaker open, skipping request", reqIDField, logger.String("url", req.URL.String()))
+			return nil, ErrCircuitOpen
+		}
+		defer func() {
+			if !upstreamReached || ctx.Err() != nil {
+				h.breaker.release()
+				return
+			}
+			h.breaker.record(!upstreamFailed)
+		}()
+	}
+
+	// With a retry budget, no attempt starts after the deadline and no
+	// backoff sleeps past it.
+	var deadline time.Time
+	if h.config.RetryBudgetMs > 0 {
+		deadline = time.Now().Add(time.Duration(h.config.RetryBudgetMs) * time.Millisecond)
+	}
+	budgetLeft := func(wait time.Duration) bool {
+		if deadline.IsZero() || !time.Now().Add(wait).After(deadline) {
+			return true
 		}
+		logger.WarnContext(ctx, "Retry budget exhausted", reqIDField, logger.Int("retry_budget_ms", h.config.RetryBudgetMs))
+		return false
+	}
 
-		if bodyData != nil {
-			req.Header.Set("Content-Type", "application/json")
+	for attempt := 1; attempt <= h.config.MaxRetries+1; attempt++ {
+		attemptReq := base.Clone(ctx)
+		if getBody != nil {
+			body, err := getBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to reset request body: %w", err)
+			}
+			attemptReq.Body = body
 		}
-		req.Header.Set("X-Request-ID", uuid.New().String())
 
-		logger.InfoContext(reqCtx, "Sending request", logger.String("method", method), logger.String("url", url), logger.Int("attempt", attempt))
+		logger.InfoContext(ctx, "Sending request", reqIDField, logger.String("method", req.Method), logger.String("url", req.URL.String()), logger.Int("attempt", attempt))
 
-		resp, err := h.client.Do(req)
+		resp, err := client.Do(attemptReq)
 		if err != nil {
-			logger.ErrorContext(reqCtx, "Request attempt failed", logger.Int("attempt", attempt), logger.ErrField(err))
-			if attempt == h.config.MaxRetries+1 {
-				return fmt.Errorf("request failed: %w", err)
+			logger.ErrorContext(ctx, "Request attempt failed", reqIDField, logger.Int("attempt", attempt), logger.ErrField(err))
+			if attempt == h.config.MaxRetries+1 || !budgetLeft(0) {
+				upstreamReached, upstreamFailed = true, true
+				return nil, fmt.Errorf("request failed: %w", transportError(err))
 			}
 			continue
 		}
-		defer resp.Body.Close()
 
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			if output != nil {
-				bodyBytes, err := io.ReadAll(resp.Body)
-				if err != nil {
-					logger.ErrorContext(reqCtx, "Failed to read response body", logger.ErrField(err))
-					return fmt.Errorf("failed to read response body: %w", err)
-				}
-				if err := json.Unmarshal(bodyBytes, output); err != nil {
-					return fmt.Errorf("failed to unmarshal response: %w", err)
-				}
-			}
-			logger.InfoContext(reqCtx, "Request completed successfully")
-			return nil
-		}
-
-		if resp.StatusCode < 500 || attempt == h.config.MaxRetries+1 {
-			bodyBytes, _ := io.ReadAll(resp.Body)
-			logger.InfoContext(reqCtx, "Error response body", logger.String("body", string(bodyBytes)))
-			logger.InfoContext(reqCtx, "Response headers", logger.Any("headers", resp.Header))
-			var errResp map[string]string
-			if len(bodyBytes) > 0 {
-				if err := json.Unmarshal(bodyBytes, &errResp); err == nil && errResp["error"] != "" {
-					logger.ErrorContext(reqCtx, "Request failed with status", logger.Int("status", resp.StatusCode), logger.String("error", errResp["error"]))
-					return fmt.Errorf("request failed with status %d: %s", resp.StatusCode, errResp["error"])
-				}
-			}
-			logger.ErrorContext(reqCtx, "Request failed with status", logger.Int("status", resp.StatusCode), logger.String("error", "unknown error"))
-			return fmt.Errorf("request failed with status %d: unknown error", resp.StatusCode)
+		delay := h.backoff(attempt)
+		if resp.StatusCode < 500 || attempt == h.config.MaxRetries+1 || !budgetLeft(delay) {
+			upstreamReached, upstreamFailed = true, resp.StatusCode >= 500
+			return resp, nil
 		}
 
-		logger.ErrorContext(reqCtx, "Request attempt failed with status", logger.Int("attempt", attempt), logger.Int("status", resp.StatusCode))
+		resp.Body.Close()
+		logger.ErrorContext(ctx, "Request attempt failed with status", reqIDField, logger.Int("attempt", attempt), logger.Int("status", resp.StatusCode))
+		time.Sleep(delay)
+	}
 
-		if h.config.DisableBackoff {
-			continue
+	upstreamReached, upstreamFailed = true, true
+	return nil, fmt.Errorf("all retry attempts failed")
+}
+
+// newStreamClient returns a copy of client for CallStream. http.Client.Timeout
+// also covers reading the body, which would cut off long downloads, so the copy
+// has none and instead bounds the wait for the response headers by the same
+// timeout. The body is bounded only by the caller's context.
+func newStreamClient(client *http.Client) *http.Client {
+	transport, ok := client.Transport.(*http.Transport)
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport)
+	}
+	transport = transport.Clone()
+	transport.ResponseHeaderTimeout = client.Timeout
+	stream := *client
+	stream.Transport = transport
+	stream.Timeout = 0
+	return &stream
+}
+
+// newRequest builds a request carrying the request id, default headers and
+// per-call headers. contentType is set when there is a body.
+func (h *HTTPClient) newRequest(ctx context.Context, method, url string, body io.Reader, contentType, requestID string, callCfg *callConfig) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to create request: %w", ErrValidation, err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	req.Header.Set(RequestIDHeader, requestID)
+	for k, v := range h.config.DefaultHeaders {
+		req.Header.Set(k, v)
+	}
+	for k, v := range callCfg.headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+// redirectPolicy follows at most max redirects. Once the limit is reached the
+// last redirect response is returned to the caller instead of an error, so a
+// limit of 0 surfaces the first 3xx response and its Location header.
+func redirectPolicy(max int) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		if len(via) > max {
+			return http.ErrUseLastResponse
 		}
+		return nil
+	}
+}
+
+// proxyFunc returns the proxy selection for the client transport: every
+// request goes through raw when it is set, otherwise the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment variables apply.
+func proxyFunc(raw string) (func(*http.Request) (*url.URL, error), error) {
+	if raw == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("proxy URL %q needs a scheme and host", raw)
+	}
+	logger.Info("Using HTTP client proxy", logger.String("proxy", u.Redacted()))
+	return http.ProxyURL(u), nil
+}
+
+// backoff returns how long to wait before the retry following attempt, or
+// zero when backoff is disabled.
+func (h *HTTPClient) backoff(attempt int) time.Duration {
+	if h.config.DisableBackoff {
+		return 0
+	}
+	backoff := h.config.BackoffBaseMs * int64(1<<uint(attempt-1))
+	if backoff > h.config.BackoffMaxMs {
+		backoff = h.config.BackoffMaxMs
+	}
+	return time.Duration(backoff) * time.Millisecond
+}
+
+// Sentinel errors wrapped by HTTPClient failures, for use with errors.Is.
+var (
+	// ErrTimeout is wrapped when a request exceeds the client timeout or the
+	// deadline of its context.
+	ErrTimeout = errors.New("request timed out")
+	// ErrNetwork is wrapped when no response was received because the
+	// connection failed, for example when the server refused it.
+	ErrNetwork = errors.New("network error")
+	// ErrValidation is wrapped when a request is rejected before it is sent,
+	// such as an invalid method, URL or input.
+	ErrValidation = errors.New("invalid request")
+)
+
+// HTTPStatusError is returned by Call, CallForm and CallStream when the
+// server responds with a non-2xx status.
+type HTTPStatusError struct {
+	Code    int    // HTTP status code
+	Body    []byte // Response body
+	Message string // "error" field of a JSON body, if any
+}
 
-		backoff := h.config.BackoffBaseMs * int64(1<<uint(attempt-1))
-		if backoff > h.config.BackoffMaxMs {
-			backoff = h.config.BackoffMaxMs
+func (e *HTTPStatusError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = "unknown error"
+	}
+	return fmt.Sprintf("request failed with status %d: %s", e.Code, msg)
+}
+
+// transportError wraps an error from sending a request or reading its
+// response with ErrTimeout or ErrNetwork. Cancellation is left unwrapped.
+func transportError(err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	case errors.Is(err, context.Canceled):
+		return err
+	default:
+		return fmt.Errorf("%w: %w", ErrNetwork, err)
+	}
+}
+
+// responseError reads an error response and converts it into an
+// HTTPStatusError, using the "error" field of a JSON body as its message.
+func responseError(ctx context.Context, resp *http.Response, reqIDField interface{}) error {
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	logger.InfoContext(ctx, "Error response body", reqIDField, logger.String("body", string(bodyBytes)))
+	logger.InfoContext(ctx, "Response headers", reqIDField, logger.Any("headers", resp.Header))
+	statusErr := &HTTPStatusError{Code: resp.StatusCode, Body: bodyBytes}
+	var errResp map[string]string
+	if len(bodyBytes) > 0 {
+		if err := json.Unmarshal(bodyBytes, &errResp); err == nil {
+			statusErr.Message = errResp["error"]
 		}
-		time.Sleep(time.Duration(backoff) * time.Millisecond)
 	}
+	logger.ErrorContext(ctx, "Request failed with status", reqIDField, logger.Int("status", resp.StatusCode), logger.String("error", statusErr.Error()))
+	return statusErr
+}
+
+// maxSnippetLen caps the body excerpt included in UnexpectedContentTypeError.
+const maxSnippetLen = 200
+
+// UnexpectedContentTypeError is returned when a successful response to Call
+// cannot be decoded into the output and its Content-Type is not JSON.
+type UnexpectedContentTypeError struct {
+	ContentType string // Content-Type header of the response
+	Snippet     string // Start of the response body
+}
+
+func (e *UnexpectedContentTypeError) Error() string {
+	return fmt.Sprintf("unexpected response content type %q (expected JSON): %s", e.ContentType, e.Snippet)
+}
+
+// checkContentType returns an UnexpectedContentTypeError unless contentType
+// is JSON (application/json or a +json type). A missing Content-Type is
+// accepted and left to the JSON decoder.
+func checkContentType(contentType string, body []byte) error {
+	if contentType == "" {
+		return nil
+	}
+	if isJSONContentType(contentType) {
+		return nil
+	}
+	snippet := strings.TrimSpace(string(body))
+	if len(snippet) > maxSnippetLen {
+		snippet = snippet[:maxSnippetLen] + "..."
+	}
+	return &UnexpectedContentTypeError{ContentType: contentType, Snippet: snippet}
+}
 
-	return fmt.Errorf("all retry attempts failed")
+// decodeOutput unmarshals a response body into output, if one was given.
+// With WithStrictJSON, unknown fields and trailing data are rejected.
+func (h *HTTPClient) decodeOutput(body []byte, output interface{}) error {
+	if output == nil {
+		return nil
+	}
+	if !h.strictJSON {
+		if err := json.Unmarshal(body, output); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(output); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return fmt.Errorf("failed to unmarshal response: unexpected data after JSON value")
+	}
+	return nil
 }
@@ -0,0 +1,31 @@
+package httpc
+
+import (
+	"net/http"
+
+	"github.com/T-Prohmpossadhorn/go-core/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// busyBody is the response sent when the server is handling its maximum
+// number of concurrent connections.
+const busyBody = `{"error":"server busy"}`
+
+// maxConnectionsMiddleware limits the number of requests handled at the same
+// time to max. Requests arriving while all slots are taken are answered
+// immediately with 503 Service Unavailable instead of queuing.
+func maxConnectionsMiddleware(max int) gin.HandlerFunc {
+	sem := make(chan struct{}, max)
+	return func(c *gin.Context) {
+		select {
+		case sem <- struct{}{}:
+			defer func() { <-sem }()
+			c.Next()
+		default:
+			logger.WarnContext(c.Request.Context(), "Rejecting request, server busy", logger.Int("max_connections", max), logger.String("path", c.Request.URL.Path))
+			c.Header("Retry-After", "1")
+			c.Data(http.StatusServiceUnavailable, "application/json", []byte(busyBody))
+			c.Abort()
+		}
+	}
+}
@@ -2,7 +2,10 @@ package httpc
 
 import (
 	"fmt"
+	"net/http"
 	"reflect"
+	"strings"
+	"unicode"
 
 	"github.com/T-Prohmpossadhorn/go-core/logger"
 )
@@ -16,10 +19,15 @@ func getServiceInfo(service interface{}) ([]MethodInfo, error) {
 	svcType := reflect.TypeOf(service)
 	svcValue := reflect.ValueOf(service)
 
-	// Check for RegisterMethods method
+	// Check for RegisterMethods method, falling back to naming conventions
 	registerMethod, ok := svcType.MethodByName("RegisterMethods")
 	if !ok {
-		return nil, fmt.Errorf("no RegisterMethods method found")
+		methods := conventionMethods(svcType, svcValue)
+		if len(methods) == 0 {
+			return nil, fmt.Errorf("no RegisterMethods method found and no methods match the naming conventions")
+		}
+		logger.Info("Derived methods from naming conventions", logger.Int("count", len(methods)))
+		return methods, nil
 	}
 
 	// Verify RegisterMethods signature
@@ -64,3 +72,57 @@ func getServiceInfo(service interface{}) ([]MethodInfo, error) {
 	logger.Info("Retrieved methods", "count", len(methods))
 	return methods, nil
 }
+
+// conventionPrefixes maps method name prefixes to the HTTP method used for
+// services without RegisterMethods
+var conventionPrefixes = []struct {
+	prefix     string
+	httpMethod string
+}{
+	{"Get", http.MethodGet},
+	{"Post", http.MethodPost},
+	{"Put", http.MethodPut},
+	{"Patch", http.MethodPatch},
+	{"Delete", http.MethodDelete},
+	{"Head", http.MethodHead},
+}
+
+// conventionMethods derives MethodInfo from exported methods whose names start
+// with an HTTP verb followed by an upper-case letter, e.g. GetUser is served
+// as GET /GetUser. Methods without a matching prefix or with a signature other
+// than func(Input) (Output, error) are skipped.
+func conventionMethods(svcType reflect.Type, svcValue reflect.Value) []MethodInfo {
+	errType := reflect.TypeOf((*error)(nil)).Elem()
+	var methods []MethodInfo
+	for i := 0; i < svcType.NumMethod(); i++ {
+		meth := svcType.Method(i)
+		httpMethod := conventionHTTPMethod(meth.Name)
+		if httpMethod == "" {
+			continue
+		}
+		if meth.Type.NumIn() != 2 || meth.Type.NumOut() != 2 || meth.Type.Out(1) != errType {
+			logger.Warn("Skipping method with invalid signature", logger.String("method", meth.Name))
+			continue
+		}
+		methods = append(methods, MethodInfo{
+			Name:       meth.Name,
+			HTTPMethod: httpMethod,
+			InputType:  meth.Type.In(1),
+			OutputType: meth.Type.Out(0),
+			Func:       svcValue.Method(i),
+		})
+	}
+	return methods
+}
+
+// conventionHTTPMethod returns the HTTP method for a method name following the
+// naming conventions, or an empty string
+func conventionHTTPMethod(name string) string {
+	for _, c := range conventionPrefixes {
+		rest := strings.TrimPrefix(name, c.prefix)
+		if rest != name && rest != "" && unicode.IsUpper(rune(rest[0])) {
+			return c.httpMethod
+		}
+	}
+	return ""
+}
@@ -40,6 +40,16 @@ func TestGetServiceInfo(t *testing.T) {
 		assert.Contains(t, err.Error(), "service cannot be nil")
 	})
 
+	t.Run("Convention Methods", func(t *testing.T) {
+		info, err := getServiceInfo(catalogService{})
+		assert.NoError(t, err)
+		routes := map[string]string{}
+		for _, m := range info {
+			routes[m.Name] = m.HTTPMethod
+		}
+		assert.Equal(t, map[string]string{"GetItem": "GET", "PatchItem": "PATCH"}, routes)
+	})
+
 	t.Run("No RegisterMethods", func(t *testing.T) {
 		type NoRegisterService struct{}
 		svc := &NoRegisterService{}
@@ -49,3 +59,20 @@ func TestGetServiceInfo(t *testing.T) {
 		assert.Contains(t, err.Error(), "no RegisterMethods method found")
 	})
 }
+
+func TestConventionHTTPMethod(t *testing.T) {
+	tests := map[string]string{
+		"GetItem":    "GET",
+		"PostOrder":  "POST",
+		"PutUser":    "PUT",
+		"PatchUser":  "PATCH",
+		"DeleteUser": "DELETE",
+		"HeadStatus": "HEAD",
+		"Get":        "",
+		"Getaway":    "",
+		"Describe":   "",
+	}
+	for name, want := range tests {
+		assert.Equal(t, want, conventionHTTPMethod(name), name)
+	}
+}
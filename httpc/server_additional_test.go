@@ -1,13 +1,24 @@
 package httpc
 
 import (
+	"bufio"
 	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/T-Prohmpossadhorn/go-core/config"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 )
 
 // headService provides a HEAD method for testing.
@@ -18,6 +29,73 @@ func (s headService) RegisterMethods() []MethodInfo {
 	return []MethodInfo{{Name: "HeadMethod", HTTPMethod: http.MethodHead, InputType: reflect.TypeOf(""), OutputType: reflect.TypeOf(""), Func: reflect.ValueOf(s).MethodByName("HeadMethod")}}
 }
 
+// webhookService receives raw request bodies for testing.
+type webhookService struct {
+	received chan []byte
+}
+
+func (s webhookService) Receive(payload []byte) (string, error) {
+	s.received <- payload
+	return "ok", nil
+}
+func (s webhookService) RegisterMethods() []MethodInfo {
+	return []MethodInfo{{Name: "Receive", HTTPMethod: http.MethodPost, ContentType: "application/octet-stream", InputType: reflect.TypeOf([]byte(nil)), OutputType: reflect.TypeOf(""), Func: reflect.ValueOf(s).MethodByName("Receive")}}
+}
+
+// slowService has a method that sleeps for the duration in its input.
+type slowService struct{}
+
+func (s slowService) Slow(delay string) (string, error) {
+	d, err := time.ParseDuration(delay)
+	if err != nil {
+		return "", err
+	}
+	time.Sleep(d)
+	return "done", nil
+}
+func (s slowService) RegisterMethods() []MethodInfo {
+	return []MethodInfo{{Name: "Slow", HTTPMethod: http.MethodGet, InputType: reflect.TypeOf(""), OutputType: reflect.TypeOf(""), Func: reflect.ValueOf(s).MethodByName("Slow")}}
+}
+
+// updateUserInput draws its fields from the path, query and body.
+type updateUserInput struct {
+	ID     string `uri:"id" validate:"required"`
+	Notify bool   `form:"notify"`
+	Name   string `json:"name" validate:"required"`
+}
+
+// updateUserOutput echoes every bound input field.
+type updateUserOutput struct {
+	ID     string `json:"id"`
+	Notify bool   `json:"notify"`
+	Name   string `json:"name"`
+}
+
+// userService exposes a method with a path parameter for testing.
+type userService struct{}
+
+func (s userService) UpdateUser(in updateUserInput) (updateUserOutput, error) {
+	return updateUserOutput(in), nil
+}
+func (s userService) RegisterMethods() []MethodInfo {
+	return []MethodInfo{{Name: "UpdateUser", HTTPMethod: http.MethodPut, Path: "users/:id", InputType: reflect.TypeOf(updateUserInput{}), OutputType: reflect.TypeOf(updateUserOutput{}), Func: reflect.ValueOf(s).MethodByName("UpdateUser")}}
+}
+
+// listInput is bound from the query only, with a default page size.
+type listInput struct {
+	Limit int `form:"limit,default=10"`
+}
+
+// listService exposes a GET method whose input has query defaults.
+type listService struct{}
+
+func (s listService) List(in listInput) (int, error) {
+	return in.Limit, nil
+}
+func (s listService) RegisterMethods() []MethodInfo {
+	return []MethodInfo{{Name: "List", HTTPMethod: http.MethodGet, Path: "users", InputType: reflect.TypeOf(listInput{}), OutputType: reflect.TypeOf(0), Func: reflect.ValueOf(s).MethodByName("List")}}
+}
+
 // TestHandleMethodInvalidJSON checks JSON binding failure path.
 func TestHandleMethodInvalidJSON(t *testing.T) {
 	cfgMap, _ := toConfigMap(ServerConfig{OtelEnabled: false, Port: 8080})
@@ -60,3 +138,819 @@ func TestHandleMethodHead(t *testing.T) {
 		t.Fatalf("expected 200, got %d", resp.StatusCode)
 	}
 }
+
+// TestServerRequestID verifies the request id is echoed or generated.
+func TestServerRequestID(t *testing.T) {
+	cfgMap, _ := toConfigMap(ServerConfig{OtelEnabled: false, Port: 8080})
+	c, _ := config.New(config.WithDefault(cfgMap))
+	srv, _ := NewServer(c)
+	if err := srv.RegisterService(&TestService{}, WithPathPrefix("/v1")); err != nil {
+		t.Fatalf("register service failed: %v", err)
+	}
+	ts := httptest.NewServer(srv.engine)
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/Hello?name=id", nil)
+	req.Header.Set(RequestIDHeader, "req-abc")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if got := resp.Header.Get(RequestIDHeader); got != "req-abc" {
+		t.Fatalf("expected request id req-abc, got %q", got)
+	}
+
+	resp, err = http.Get(ts.URL + "/health")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if resp.Header.Get(RequestIDHeader) == "" {
+		t.Fatal("expected generated request id")
+	}
+}
+
+// TestRegisterStream reads two Server-Sent Events from a stream endpoint.
+func TestRegisterStream(t *testing.T) {
+	cfgMap, _ := toConfigMap(ServerConfig{OtelEnabled: false, Port: 8080})
+	c, _ := config.New(config.WithDefault(cfgMap))
+	srv, _ := NewServer(c)
+	srv.RegisterStream("/events", func(ctx context.Context, send func(event string)) error {
+		send("first")
+		send("second\nline")
+		return nil
+	})
+	ts := httptest.NewServer(srv.engine)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	var events []string
+	var data []string
+	for scanner.Scan() && len(events) < 2 {
+		line := scanner.Text()
+		if line == "" {
+			events = append(events, strings.Join(data, "\n"))
+			data = nil
+			continue
+		}
+		data = append(data, strings.TrimPrefix(line, "data: "))
+	}
+	if len(events) != 2 || events[0] != "first" || events[1] != "second\nline" {
+		t.Fatalf("unexpected events %q", events)
+	}
+}
+
+// TestRegisterWebSocket dials a WebSocket endpoint and reads an echoed frame.
+func TestRegisterWebSocket(t *testing.T) {
+	cfgMap, _ := toConfigMap(ServerConfig{OtelEnabled: false, Port: 8080})
+	c, _ := config.New(config.WithDefault(cfgMap))
+	srv, _ := NewServer(c)
+	srv.RegisterWebSocket("/ws", func(conn *websocket.Conn) {
+		for {
+			mt, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(mt, msg); err != nil {
+				return
+			}
+		}
+	})
+	ts := httptest.NewServer(srv.engine)
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("ping")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	mt, msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	if mt != websocket.TextMessage || string(msg) != "ping" {
+		t.Fatalf("unexpected echo %d %q", mt, msg)
+	}
+}
+
+// TestHandleMethodRawBody verifies non-JSON bodies reach raw handlers intact.
+func TestHandleMethodRawBody(t *testing.T) {
+	cfgMap, _ := toConfigMap(ServerConfig{OtelEnabled: false, Port: 8080})
+	c, _ := config.New(config.WithDefault(cfgMap))
+	srv, _ := NewServer(c)
+	ws := webhookService{received: make(chan []byte, 1)}
+	if err := srv.RegisterService(ws, WithPathPrefix("/hooks")); err != nil {
+		t.Fatalf("register service failed: %v", err)
+	}
+	ts := httptest.NewServer(srv.engine)
+	defer ts.Close()
+
+	payload := []byte("{not json\x00\xff\nplain text")
+	resp, err := http.Post(ts.URL+"/hooks/Receive", "text/plain", bytes.NewReader(payload))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if got := <-ws.received; !bytes.Equal(got, payload) {
+		t.Fatalf("expected payload %q, got %q", payload, got)
+	}
+}
+
+// TestServerRequestTimeout verifies slow handlers get a 504 once the request
+// timeout elapses while fast ones are unaffected.
+func TestServerRequestTimeout(t *testing.T) {
+	c, _ := config.New(config.WithDefaultStruct(ServerConfig{Port: 8080, RequestTimeoutMs: 50}))
+	srv, err := NewServer(c)
+	if err != nil {
+		t.Fatalf("new server failed: %v", err)
+	}
+	if err := srv.RegisterService(slowService{}, WithPathPrefix("/v1")); err != nil {
+		t.Fatalf("register service failed: %v", err)
+	}
+	ts := httptest.NewServer(srv.engine)
+	defer ts.Close()
+
+	start := time.Now()
+	resp, err := http.Get(ts.URL + "/v1/Slow?name=500ms")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d", resp.StatusCode)
+	}
+	if string(body) != `{"error":"request timed out"}` {
+		t.Fatalf("unexpected body %q", body)
+	}
+	if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+		t.Fatalf("timeout response took %v", elapsed)
+	}
+
+	resp, err = http.Get(ts.URL + "/v1/Slow?name=1ms")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+}
+
+// TestServerRequestTimeoutSkipsStreams verifies an event stream keeps
+// sending after server_request_timeout_ms has elapsed.
+func TestServerRequestTimeoutSkipsStreams(t *testing.T) {
+	c, _ := config.New(config.WithDefaultStruct(ServerConfig{Port: 8080, RequestTimeoutMs: 50}))
+	srv, err := NewServer(c)
+	if err != nil {
+		t.Fatalf("new server failed: %v", err)
+	}
+	srv.RegisterStream("/events", func(ctx context.Context, send func(event string)) error {
+		send("first")
+		select {
+		case <-time.After(150 * time.Millisecond):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		send("second")
+		return nil
+	})
+	ts := httptest.NewServer(srv.engine)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/events")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if string(body) != "data: first\n\ndata: second\n\n" {
+		t.Fatalf("unexpected body %q", body)
+	}
+}
+
+// TestHandleMethodBindsPathQueryAndBody verifies a single input struct is
+// populated from path, query and body and validated once.
+func TestHandleMethodBindsPathQueryAndBody(t *testing.T) {
+	c, _ := config.New(config.WithDefaultStruct(ServerConfig{Port: 8080}))
+	srv, _ := NewServer(c)
+	if err := srv.RegisterService(userService{}, WithPathPrefix("/v1")); err != nil {
+		t.Fatalf("register service failed: %v", err)
+	}
+	ts := httptest.NewServer(srv.engine)
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, ts.URL+"/v1/users/42?notify=true", bytes.NewBufferString(`{"name":"Alice"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", resp.StatusCode, body)
+	}
+	var got updateUserOutput
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if want := (updateUserOutput{ID: "42", Notify: true, Name: "Alice"}); got != want {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+
+	// Validation runs once over all sources: a missing body field fails
+	req, _ = http.NewRequest(http.MethodPut, ts.URL+"/v1/users/42", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(body), "Name") {
+		t.Fatalf("expected 400 mentioning Name, got %d: %s", resp.StatusCode, body)
+	}
+}
+
+// TestHandleMethodQueryDefaults verifies GET inputs get their `form`
+// defaults when the request has no query string.
+func TestHandleMethodQueryDefaults(t *testing.T) {
+	c, _ := config.New(config.WithDefaultStruct(ServerConfig{Port: 8080}))
+	srv, _ := NewServer(c)
+	if err := srv.RegisterService(listService{}, WithPathPrefix("/v1")); err != nil {
+		t.Fatalf("register service failed: %v", err)
+	}
+	ts := httptest.NewServer(srv.engine)
+	defer ts.Close()
+
+	for path, want := range map[string]string{"/v1/users": "10", "/v1/users?limit=5": "5"} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || string(body) != want {
+			t.Fatalf("%s: expected 200 %s, got %d: %s", path, want, resp.StatusCode, body)
+		}
+	}
+}
+
+// catalogService has no RegisterMethods; its routes come from method names.
+// It is the convention-only fixture, while MultiMethodService registers its
+// methods explicitly
+type catalogService struct{}
+
+type patchItemInput struct {
+	Name string `json:"name" validate:"required"`
+}
+
+func (catalogService) GetItem(name string) (string, error) { return "item " + name, nil }
+
+func (catalogService) PatchItem(in patchItemInput) (string, error) { return "renamed " + in.Name, nil }
+
+// Describe and Getaway do not follow the conventions and are not registered
+func (catalogService) Describe(string) (string, error) { return "", nil }
+func (catalogService) Getaway(string) (string, error)  { return "", nil }
+
+// TestRegisterServiceByConvention verifies routes are derived from method
+// name prefixes when a service has no RegisterMethods.
+func TestRegisterServiceByConvention(t *testing.T) {
+	c, _ := config.New(config.WithDefaultStruct(ServerConfig{Port: 8080}))
+	srv, _ := NewServer(c)
+	if err := srv.RegisterService(catalogService{}, WithPathPrefix("/api")); err != nil {
+		t.Fatalf("register service failed: %v", err)
+	}
+	ts := httptest.NewServer(srv.engine)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/GetItem?name=lamp")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != `"item lamp"` {
+		t.Fatalf("expected 200 \"item lamp\", got %d: %s", resp.StatusCode, body)
+	}
+
+	req, _ := http.NewRequest(http.MethodPatch, ts.URL+"/api/PatchItem", bytes.NewBufferString(`{"name":"desk"}`))
+	req.Header.Set("Content-Type", "application/json")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != `"renamed desk"` {
+		t.Fatalf("expected 200 \"renamed desk\", got %d: %s", resp.StatusCode, body)
+	}
+
+	for _, path := range []string{"/api/Describe", "/api/Getaway"} {
+		resp, err = http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("expected 404 for %s, got %d", path, resp.StatusCode)
+		}
+	}
+}
+
+// TestHandleMethodUnsupportedContentType verifies non-JSON bodies are
+// rejected with 415 before binding.
+func TestHandleMethodUnsupportedContentType(t *testing.T) {
+	c, _ := config.New(config.WithDefaultStruct(ServerConfig{Port: 8080}))
+	srv, _ := NewServer(c)
+	if err := srv.RegisterService(TestService{}, WithPathPrefix("/api")); err != nil {
+		t.Fatalf("register service failed: %v", err)
+	}
+	ts := httptest.NewServer(srv.engine)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/api/Create", "text/xml", bytes.NewBufferString(`<user><name>Alice</name></user>`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnsupportedMediaType || !strings.Contains(string(body), `unsupported content type \"text/xml\"`) {
+		t.Fatalf("expected 415 for text/xml, got %d: %s", resp.StatusCode, body)
+	}
+
+	resp, err = http.Post(ts.URL+"/api/Create", "application/json; charset=utf-8", bytes.NewBufferString(`{"name":"Alice","email":"alice@example.com"}`))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 for JSON, got %d: %s", resp.StatusCode, body)
+	}
+}
+
+// blockingService holds each request until release is closed.
+type blockingService struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s blockingService) Block(name string) (string, error) {
+	s.started <- struct{}{}
+	<-s.release
+	return name, nil
+}
+func (s blockingService) RegisterMethods() []MethodInfo {
+	return []MethodInfo{{Name: "Block", HTTPMethod: http.MethodGet, InputType: reflect.TypeOf(""), OutputType: reflect.TypeOf(""), Func: reflect.ValueOf(s).MethodByName("Block")}}
+}
+
+// TestServerMaxConnections verifies requests beyond the limit get 503.
+func TestServerMaxConnections(t *testing.T) {
+	c, _ := config.New(config.WithDefaultStruct(ServerConfig{Port: 8080, MaxConnections: 2}))
+	srv, err := NewServer(c)
+	if err != nil {
+		t.Fatalf("new server failed: %v", err)
+	}
+	svc := blockingService{started: make(chan struct{}, 2), release: make(chan struct{})}
+	if err := srv.RegisterService(svc, WithPathPrefix("/v1")); err != nil {
+		t.Fatalf("register service failed: %v", err)
+	}
+	ts := httptest.NewServer(srv.engine)
+	defer ts.Close()
+
+	statuses := make(chan int, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			resp, err := http.Get(ts.URL + "/v1/Block?name=held")
+			if err != nil {
+				statuses <- 0
+				return
+			}
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-svc.started:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for requests to start")
+		}
+	}
+
+	resp, err := http.Get(ts.URL + "/health")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable || string(body) != `{"error":"server busy"}` {
+		t.Fatalf("expected 503 while saturated, got %d: %s", resp.StatusCode, body)
+	}
+
+	close(svc.release)
+	for i := 0; i < 2; i++ {
+		if status := <-statuses; status != http.StatusOK {
+			t.Fatalf("expected held request to succeed, got %d", status)
+		}
+	}
+
+	// Slots are freed just after the held responses are written
+	deadline := time.Now().Add(time.Second)
+	for {
+		resp, err = http.Get(ts.URL + "/health")
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 200 after release, got %d", resp.StatusCode)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestServerInvalidMaxConnections verifies negative limits are rejected.
+func TestServerInvalidMaxConnections(t *testing.T) {
+	c, _ := config.New(config.WithDefault(map[string]interface{}{"port": 8080, "server_max_connections": -1}))
+	if _, err := NewServer(c); err == nil || !strings.Contains(err.Error(), "invalid server_max_connections") {
+		t.Fatalf("expected invalid server_max_connections error, got %v", err)
+	}
+}
+
+// TestServerNotFound verifies unknown paths get a JSON 404 and that the
+// handler can be replaced.
+func TestServerNotFound(t *testing.T) {
+	c, _ := config.New(config.WithDefault(map[string]interface{}{"port": 8080}))
+	srv, err := NewServer(c)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	ts := httptest.NewServer(srv.engine)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/does/not/exist")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Fatalf("expected JSON content type, got %q", ct)
+	}
+	if string(body) != `{"error":"not found"}` {
+		t.Fatalf("unexpected body: %s", body)
+	}
+
+	srv.SetNotFoundHandler(func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "no such page", "path": c.Request.URL.Path})
+	})
+	resp, err = http.Get(ts.URL + "/missing")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound || string(body) != `{"error":"no such page","path":"/missing"}` {
+		t.Fatalf("unexpected custom response %d: %s", resp.StatusCode, body)
+	}
+}
+
+// TestServerOnShutdown verifies shutdown hooks run in registration order and
+// their errors are returned.
+func TestServerOnShutdown(t *testing.T) {
+	c, _ := config.New(config.WithDefault(map[string]interface{}{"port": 8080}))
+	srv, err := NewServer(c)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	var calls []string
+	srv.OnShutdown(func(ctx context.Context) error {
+		calls = append(calls, "brokers")
+		return nil
+	})
+	srv.OnShutdown(func(ctx context.Context) error {
+		calls = append(calls, "logger")
+		return nil
+	})
+	if err := srv.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if !reflect.DeepEqual(calls, []string{"brokers", "logger"}) {
+		t.Fatalf("unexpected hook calls: %v", calls)
+	}
+
+	errFirst := errors.New("first failed")
+	calls = nil
+	srv.onShutdown = nil
+	srv.OnShutdown(func(ctx context.Context) error {
+		calls = append(calls, "first")
+		return errFirst
+	})
+	srv.OnShutdown(func(ctx context.Context) error {
+		calls = append(calls, "second")
+		return nil
+	})
+	err = srv.Shutdown(context.Background())
+	if !errors.Is(err, errFirst) {
+		t.Fatalf("expected hook error, got %v", err)
+	}
+	if !reflect.DeepEqual(calls, []string{"first", "second"}) {
+		t.Fatalf("expected every hook to run, got %v", calls)
+	}
+}
+
+// TestRegisterServiceMethodMiddleware verifies middleware attached to one
+// method does not apply to the others.
+func TestRegisterServiceMethodMiddleware(t *testing.T) {
+	c, _ := config.New(config.WithDefault(map[string]interface{}{"port": 8080}))
+	srv, err := NewServer(c)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	auth := func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "Bearer secret" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+		c.Next()
+	}
+	if err := srv.RegisterService(TestService{}, WithPathPrefix("/v1"), WithMethodMiddleware("Create", auth)); err != nil {
+		t.Fatalf("register service failed: %v", err)
+	}
+	ts := httptest.NewServer(srv.engine)
+	defer ts.Close()
+
+	create := func(token string) int {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/Create", bytes.NewBufferString(`{"name":"Ann","email":"ann@example.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if code := create(""); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", code)
+	}
+	if code := create("wrong"); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 with wrong token, got %d", code)
+	}
+	if code := create("secret"); code != http.StatusOK {
+		t.Fatalf("expected 200 with token, got %d", code)
+	}
+
+	resp, err := http.Get(ts.URL + "/v1/Hello?name=Ann")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected public method to return 200, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerGzipMinBytes(t *testing.T) {
+	c, _ := config.New(config.WithDefaultStruct(ServerConfig{Port: 8080, GzipEnabled: true, GzipMinBytes: 256}))
+	srv, err := NewServer(c)
+	if err != nil {
+		t.Fatalf("new server failed: %v", err)
+	}
+	large := strings.Repeat("a", 1024)
+	srv.engine.GET("/small", func(c *gin.Context) { c.String(http.StatusOK, "tiny") })
+	srv.engine.GET("/large", func(c *gin.Context) { c.String(http.StatusOK, large) })
+	srv.engine.GET("/image", func(c *gin.Context) { c.Data(http.StatusOK, "image/png", []byte(large)) })
+	ts := httptest.NewServer(srv.engine)
+	defer ts.Close()
+
+	get := func(path string) (*http.Response, []byte) {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("get %s: %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+
+	resp, body := get("/small")
+	if enc := resp.Header.Get("Content-Encoding"); enc != "" {
+		t.Fatalf("small response compressed with %q", enc)
+	}
+	if string(body) != "tiny" {
+		t.Fatalf("unexpected small body %q", body)
+	}
+
+	resp, body = get("/large")
+	if enc := resp.Header.Get("Content-Encoding"); enc != "gzip" {
+		t.Fatalf("expected gzip encoding, got %q", enc)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	plain, _ := io.ReadAll(zr)
+	if string(plain) != large {
+		t.Fatalf("unexpected decompressed body of %d bytes", len(plain))
+	}
+
+	resp, body = get("/image")
+	if enc := resp.Header.Get("Content-Encoding"); enc != "" {
+		t.Fatalf("image response compressed with %q", enc)
+	}
+	if len(body) != len(large) {
+		t.Fatalf("unexpected image body of %d bytes", len(body))
+	}
+
+	c, _ = config.New(config.WithDefault(map[string]interface{}{"port": 8080, "server_gzip_min_bytes": -1}))
+	if _, err := NewServer(c); err == nil {
+		t.Fatal("expected error for negative server_gzip_min_bytes")
+	}
+}
+
+// countService streams the numbers 1..n for GET /GetCounts?name=n.
+type countService struct{}
+
+type countEvent struct {
+	N int `json:"n"`
+}
+
+func (countService) GetCounts(n string) (<-chan countEvent, error) {
+	total, err := strconv.Atoi(n)
+	if err != nil {
+		return nil, err
+	}
+	ch := make(chan countEvent)
+	go func() {
+		defer close(ch)
+		for i := 1; i <= total; i++ {
+			ch <- countEvent{N: i}
+		}
+	}()
+	return ch, nil
+}
+
+func TestRegisterServiceStreamingMethod(t *testing.T) {
+	c, _ := config.New(config.WithDefault(map[string]interface{}{"port": 8080}))
+	srv, err := NewServer(c)
+	if err != nil {
+		t.Fatalf("new server failed: %v", err)
+	}
+	if err := srv.RegisterService(countService{}, WithPathPrefix("/v1")); err != nil {
+		t.Fatalf("register service failed: %v", err)
+	}
+	ts := httptest.NewServer(srv.engine)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/v1/GetCounts?name=3")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	var got []int
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var ev countEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		got = append(got, ev.N)
+	}
+	if !reflect.DeepEqual(got, []int{1, 2, 3}) {
+		t.Fatalf("unexpected stream %v", got)
+	}
+
+	resp, err = http.Get(ts.URL + "/v1/GetCounts?name=x")
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("expected 500 for method error, got %d", resp.StatusCode)
+	}
+
+	content := srv.swagger["paths"].(map[string]interface{})["/v1/GetCounts"].(map[string]interface{})["get"].(map[string]interface{})["responses"].(map[string]interface{})["200"].(map[string]interface{})["content"].(map[string]interface{})
+	if _, ok := content["application/x-ndjson"]; !ok {
+		t.Fatalf("expected ndjson response in OpenAPI doc, got %v", content)
+	}
+}
+
+// TestServerRoutes verifies Routes lists registered endpoints with their
+// prefixes, matching the routes gin serves.
+func TestServerRoutes(t *testing.T) {
+	c, _ := config.New(config.WithDefaultStruct(ServerConfig{OtelEnabled: false, Port: 8080}))
+	srv, _ := NewServer(c)
+	if got := srv.Routes(); len(got) != 0 {
+		t.Fatalf("expected no routes before registration, got %v", got)
+	}
+	if err := srv.RegisterService(&TestService{}, WithPathPrefix("/v1")); err != nil {
+		t.Fatalf("register service failed: %v", err)
+	}
+	if err := srv.RegisterService(userService{}); err != nil {
+		t.Fatalf("register service failed: %v", err)
+	}
+
+	want := []RouteInfo{
+		{Method: http.MethodGet, Path: "/v1/Hello", OperationID: "Hello"},
+		{Method: http.MethodPost, Path: "/v1/Create", OperationID: "Create"},
+		{Method: http.MethodPut, Path: "/users/:id", OperationID: "UpdateUser"},
+	}
+	got := srv.Routes()
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected routes %v, got %v", want, got)
+	}
+
+	served := map[string]bool{}
+	for _, r := range srv.engine.Routes() {
+		served[r.Method+" "+r.Path] = true
+	}
+	for _, r := range got {
+		if !served[r.Method+" "+r.Path] {
+			t.Fatalf("route %s %s not served by gin", r.Method, r.Path)
+		}
+	}
+
+	got[0].Path = "/changed"
+	if srv.Routes()[0].Path != "/v1/Hello" {
+		t.Fatal("Routes should return a copy")
+	}
+}
+
+// TestServerTrustedProxies verifies forwarded client IPs are honored only
+// when the request comes from a trusted proxy.
+func TestServerTrustedProxies(t *testing.T) {
+	for _, proxies := range []interface{}{
+		[]string{"10.0.0.0/8", "127.0.0.1"},
+		"10.0.0.0/8, 127.0.0.1",
+	} {
+		c, _ := config.New(config.WithDefault(map[string]interface{}{"server_trusted_proxies": proxies}))
+		srv, err := NewServer(c)
+		if err != nil {
+			t.Fatalf("new server failed: %v", err)
+		}
+		srv.engine.GET("/ip", func(c *gin.Context) {
+			c.String(http.StatusOK, c.ClientIP())
+		})
+
+		for _, tc := range []struct {
+			remote, want string
+		}{
+			{"10.1.2.3:5000", "203.0.113.7"},
+			{"127.0.0.1:5000", "203.0.113.7"},
+			{"198.51.100.4:5000", "198.51.100.4"},
+		} {
+			req := httptest.NewRequest(http.MethodGet, "/ip", nil)
+			req.RemoteAddr = tc.remote
+			req.Header.Set("X-Forwarded-For", "203.0.113.7")
+			w := httptest.NewRecorder()
+			srv.engine.ServeHTTP(w, req)
+			if got := w.Body.String(); got != tc.want {
+				t.Fatalf("proxies %v, remote %s: expected client ip %s, got %s", proxies, tc.remote, tc.want, got)
+			}
+		}
+	}
+
+	c, _ := config.New(config.WithDefault(map[string]interface{}{"server_trusted_proxies": "not-an-ip"}))
+	if _, err := NewServer(c); err == nil {
+		t.Fatal("expected error for invalid trusted proxy")
+	}
+}
@@ -0,0 +1,127 @@
+package httpc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/T-Prohmpossadhorn/go-core/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// StreamHandler produces Server-Sent Events by calling send for each event
+// until it returns. ctx is canceled when the client disconnects.
+type StreamHandler func(ctx context.Context, send func(event string)) error
+
+// RegisterStream registers a GET endpoint at path that streams the events
+// produced by handler as Server-Sent Events, flushing each one immediately.
+// An error returned by handler is sent as a final "error" event.
+func (s *Server) RegisterStream(path string, handler StreamHandler) {
+	s.markStream(http.MethodGet, path)
+	s.engine.GET(path, func(c *gin.Context) {
+		ctx := c.Request.Context()
+		reqIDField := logger.String("request_id", RequestIDFromContext(ctx))
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Status(http.StatusOK)
+		c.Writer.Flush()
+
+		send := func(event string) {
+			if ctx.Err() != nil {
+				return
+			}
+			writeSSE(c.Writer, "", event)
+			c.Writer.Flush()
+		}
+
+		logger.InfoContext(ctx, "Stream opened", reqIDField, logger.String("path", path))
+		if err := handler(ctx, send); err != nil {
+			logger.ErrorContext(ctx, "Stream handler failed", reqIDField, logger.ErrField(err))
+			if ctx.Err() == nil {
+				writeSSE(c.Writer, "error", err.Error())
+				c.Writer.Flush()
+			}
+			return
+		}
+		logger.InfoContext(ctx, "Stream closed", reqIDField, logger.String("path", path))
+	})
+	logger.Info("Registered stream endpoint", logger.String("path", path))
+}
+
+// writeSSE writes a single event, prefixing every line of data with "data:".
+func writeSSE(w gin.ResponseWriter, event, data string) {
+	if event != "" {
+		fmt.Fprintf(w, "event: %s\n", event)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
+
+// ndjsonContentType is the content type of responses streamed from service
+// methods that return a channel.
+const ndjsonContentType = "application/x-ndjson"
+
+// isStreamOutput reports whether a service method output type is a channel
+// the handler can receive results from, e.g. <-chan T.
+func isStreamOutput(t reflect.Type) bool {
+	return t != nil && t.Kind() == reflect.Chan && t.ChanDir()&reflect.RecvDir != 0
+}
+
+// streamResults writes each value received from ch as one line of JSON,
+// flushing after every line, until ch is closed. When the client disconnects
+// the rest of ch is drained in the background so the sender does not block.
+func streamResults(c *gin.Context, ch reflect.Value, reqIDField interface{}) {
+	ctx := c.Request.Context()
+	c.Header("Content-Type", ndjsonContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+	if ch.IsNil() {
+		return
+	}
+
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: ch},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+	}
+	count := 0
+	for {
+		chosen, v, ok := reflect.Select(cases)
+		if chosen == 1 {
+			logger.WarnContext(ctx, "Client disconnected from stream", reqIDField, logger.Int("sent", count))
+			go drain(ch)
+			return
+		}
+		if !ok {
+			break
+		}
+		line, err := json.Marshal(v.Interface())
+		if err != nil {
+			logger.ErrorContext(ctx, "Encoding stream result failed", reqIDField, logger.ErrField(err))
+			line, _ = json.Marshal(gin.H{"error": err.Error()})
+			_, _ = c.Writer.Write(append(line, '\n'))
+			go drain(ch)
+			return
+		}
+		_, _ = c.Writer.Write(append(line, '\n'))
+		c.Writer.Flush()
+		count++
+	}
+	logger.InfoContext(ctx, "Stream completed", reqIDField, logger.Int("sent", count))
+}
+
+// drain receives from ch until it is closed.
+func drain(ch reflect.Value) {
+	for {
+		if _, ok := ch.Recv(); !ok {
+			return
+		}
+	}
+}
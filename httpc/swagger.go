@@ -3,7 +3,10 @@ package httpc
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+
+	"github.com/T-Prohmpossadhorn/go-core/logger"
 )
 
 // generateSchema generates a Swagger schema for a given type
@@ -37,7 +40,14 @@ func generateSchema(t reflect.Type) map[string]interface{} {
 		validateTag := field.Tag.Get("validate")
 		fieldSchema := map[string]interface{}{}
 
-		switch field.Type.Kind() {
+		// Pointer fields accept null; they are marked in the OpenAPI 3.0 style
+		// and converted by setNullableStyle for 3.1 documents
+		fieldType, nullable := field.Type, false
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType, nullable = fieldType.Elem(), true
+		}
+
+		switch fieldType.Kind() {
 		case reflect.String:
 			fieldSchema["type"] = "string"
 			if strings.Contains(validateTag, "min=") {
@@ -93,7 +103,10 @@ func generateSchema(t reflect.Type) map[string]interface{} {
 				}
 			}
 		case reflect.Struct:
-			fieldSchema = generateSchema(field.Type)
+			fieldSchema = generateSchema(fieldType)
+		}
+		if nullable && fieldSchema["type"] != nil {
+			fieldSchema["nullable"] = true
 		}
 
 		if strings.Contains(validateTag, "required") {
@@ -124,6 +137,88 @@ func parseFloat(s string) (float64, error) {
 	return result, err
 }
 
+// Supported OpenAPI versions for SetOpenAPIVersion.
+const (
+	OpenAPIVersion30 = "3.0.3"
+	OpenAPIVersion31 = "3.1.0"
+)
+
+// jsonSchemaDialect31 is the default schema dialect of OpenAPI 3.1 documents.
+const jsonSchemaDialect31 = "https://spec.openapis.org/oas/3.1/dialect/base"
+
+// SetOpenAPIVersion sets the OpenAPI version of the generated document to
+// OpenAPIVersion30 (the default) or OpenAPIVersion31. For 3.1 the document
+// declares the JSON Schema dialect and nullable fields use a "null" type,
+// e.g. {"type":["string","null"]}, instead of 3.0's "nullable": true.
+// Endpoints already registered are converted, so it may be called at any
+// time.
+func (s *Server) SetOpenAPIVersion(version string) error {
+	if version != OpenAPIVersion30 && version != OpenAPIVersion31 {
+		return fmt.Errorf("unsupported OpenAPI version: %s", version)
+	}
+	s.swagger["openapi"] = version
+	if version == OpenAPIVersion31 {
+		s.swagger["jsonSchemaDialect"] = jsonSchemaDialect31
+	} else {
+		delete(s.swagger, "jsonSchemaDialect")
+	}
+	setNullableStyle(s.swagger["paths"], version == OpenAPIVersion31)
+	logger.Info("Using OpenAPI version", logger.String("version", version))
+	return nil
+}
+
+// setNullableStyle rewrites the nullable schemas found in node in place:
+// with openAPI31, {"type":"string","nullable":true} becomes
+// {"type":["string","null"]}, and without it the reverse.
+func setNullableStyle(node interface{}, openAPI31 bool) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if openAPI31 {
+			if t, ok := n["type"].(string); ok && n["nullable"] == true {
+				n["type"] = []string{t, "null"}
+				delete(n, "nullable")
+			}
+		} else if types, ok := n["type"].([]string); ok && len(types) == 2 && types[1] == "null" {
+			n["type"] = types[0]
+			n["nullable"] = true
+		}
+		for _, child := range n {
+			setNullableStyle(child, openAPI31)
+		}
+	case []map[string]interface{}:
+		for _, child := range n {
+			setNullableStyle(child, openAPI31)
+		}
+	}
+}
+
+// defaultErrorResponses are documented for methods that do not set
+// MethodInfo.ErrorResponses
+var defaultErrorResponses = map[int]string{
+	400: "Bad request",
+	422: "Unprocessable entity",
+	500: "Internal server error",
+}
+
+// errorResponse builds an OpenAPI response using the {"error":"string"} shape
+func errorResponse(description string) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"error": map[string]interface{}{
+							"type": "string",
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
 // updateSwaggerDoc updates the Swagger documentation for the given service
 func updateSwaggerDoc(s *Server, service interface{}, prefix string) error {
 	if s == nil {
@@ -133,7 +228,7 @@ func updateSwaggerDoc(s *Server, service interface{}, prefix string) error {
 	// Initialize swagger if not already set or missing required fields
 	if s.swagger == nil || s.swagger["openapi"] == nil || s.swagger["info"] == nil {
 		s.swagger = map[string]interface{}{
-			"openapi": "3.0.3",
+			"openapi": OpenAPIVersion30,
 			"info": map[string]interface{}{
 				"title":   "httpc API",
 				"version": "1.0.0",
@@ -154,7 +249,12 @@ func updateSwaggerDoc(s *Server, service interface{}, prefix string) error {
 			continue
 		}
 
-		path := prefix + "/" + method.Name
+		route := methodPath(method)
+		params := pathParams(route)
+		for _, name := range params {
+			route = strings.Replace(route, ":"+name, "{"+name+"}", 1)
+		}
+		path := prefix + "/" + route
 		if !strings.HasPrefix(path, "/") {
 			path = "/" + path
 		}
@@ -164,45 +264,20 @@ func updateSwaggerDoc(s *Server, service interface{}, prefix string) error {
 			pathItem = existing.(map[string]interface{})
 		}
 
+		// Streaming methods send one JSON value per line
+		responseType, outputType := "application/json", method.OutputType
+		if isStreamOutput(outputType) {
+			responseType, outputType = ndjsonContentType, outputType.Elem()
+		}
 		operation := map[string]interface{}{
 			"operationId": method.Name,
 			"responses": map[string]interface{}{
 				"200": map[string]interface{}{
 					"description": "Successful response",
 					"content": map[string]interface{}{
-						"application/json": map[string]interface{}{
-							"schema": map[string]interface{}{
-								"type": method.OutputType.Kind().String(),
-							},
-						},
-					},
-				},
-				"400": map[string]interface{}{
-					"description": "Bad request",
-					"content": map[string]interface{}{
-						"application/json": map[string]interface{}{
-							"schema": map[string]interface{}{
-								"type": "object",
-								"properties": map[string]interface{}{
-									"error": map[string]interface{}{
-										"type": "string",
-									},
-								},
-							},
-						},
-					},
-				},
-				"500": map[string]interface{}{
-					"description": "Internal server error",
-					"content": map[string]interface{}{
-						"application/json": map[string]interface{}{
+						responseType: map[string]interface{}{
 							"schema": map[string]interface{}{
-								"type": "object",
-								"properties": map[string]interface{}{
-									"error": map[string]interface{}{
-										"type": "string",
-									},
-								},
+								"type": outputType.Kind().String(),
 							},
 						},
 					},
@@ -210,24 +285,62 @@ func updateSwaggerDoc(s *Server, service interface{}, prefix string) error {
 			},
 			"summary": method.Name,
 		}
+		errorResponses := method.ErrorResponses
+		if errorResponses == nil {
+			errorResponses = defaultErrorResponses
+		}
+		responses := operation["responses"].(map[string]interface{})
+		for status, description := range errorResponses {
+			responses[strconv.Itoa(status)] = errorResponse(description)
+		}
+		if method.Description != "" {
+			operation["description"] = method.Description
+		}
+		if len(method.Tags) > 0 {
+			operation["tags"] = method.Tags
+		}
+		if method.Deprecated {
+			operation["deprecated"] = true
+		}
+
+		var parameters []map[string]interface{}
+		for _, name := range params {
+			parameters = append(parameters, map[string]interface{}{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema": map[string]interface{}{
+					"type": "string",
+				},
+			})
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
 
 		if method.HTTPMethod == "GET" {
-			operation["parameters"] = []map[string]interface{}{
-				{
-					"name":     "name",
-					"in":       "query",
-					"required": false,
-					"schema": map[string]interface{}{
-						"type": "string",
-					},
+			operation["parameters"] = append(parameters, map[string]interface{}{
+				"name":     "name",
+				"in":       "query",
+				"required": false,
+				"schema": map[string]interface{}{
+					"type": "string",
 				},
-			}
+			})
 		} else {
 			// POST, PUT, DELETE, PATCH, OPTIONS, HEAD
 			schema := generateSchema(method.InputType)
+			contentType := "application/json"
+			if isRawBody(method) {
+				contentType = method.ContentType
+				schema = map[string]interface{}{"type": "string"}
+				if method.InputType.Kind() == reflect.Slice {
+					schema["format"] = "binary"
+				}
+			}
 			operation["requestBody"] = map[string]interface{}{
 				"content": map[string]interface{}{
-					"application/json": map[string]interface{}{
+					contentType: map[string]interface{}{
 						"schema": schema,
 					},
 				},
@@ -235,6 +348,7 @@ func updateSwaggerDoc(s *Server, service interface{}, prefix string) error {
 			}
 		}
 
+		setNullableStyle(operation, s.swagger["openapi"] == OpenAPIVersion31)
 		pathItem[strings.ToLower(method.HTTPMethod)] = operation
 		paths[path] = pathItem
 	}
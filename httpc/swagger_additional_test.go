@@ -1,6 +1,31 @@
 package httpc
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+
+	"github.com/T-Prohmpossadhorn/go-core/config"
+)
+
+// taggedService declares OpenAPI metadata on its methods.
+type taggedService struct{}
+
+func (s taggedService) Old(name string) (string, error) { return name, nil }
+func (s taggedService) RegisterMethods() []MethodInfo {
+	return []MethodInfo{{
+		Name:        "Old",
+		HTTPMethod:  "GET",
+		InputType:   reflect.TypeOf(""),
+		OutputType:  reflect.TypeOf(""),
+		Func:        reflect.ValueOf(s).MethodByName("Old"),
+		Description: "Superseded by New",
+		Tags:        []string{"legacy"},
+		Deprecated:  true,
+		ErrorResponses: map[int]string{
+			404: "Not found",
+		},
+	}}
+}
 
 // TestUpdateSwaggerDocNilServer verifies error when server is nil.
 func TestUpdateSwaggerDocNilServer(t *testing.T) {
@@ -38,3 +63,126 @@ func TestUpdateSwaggerDocPrefixFormatting(t *testing.T) {
 		t.Fatalf("expected path with leading slash; got %v", paths)
 	}
 }
+
+// TestUpdateSwaggerDocTagsAndDeprecated checks MethodInfo metadata is emitted.
+func TestUpdateSwaggerDocTagsAndDeprecated(t *testing.T) {
+	srv := &Server{}
+	if err := updateSwaggerDoc(srv, taggedService{}, "/v1"); err != nil {
+		t.Fatalf("updateSwaggerDoc returned error: %v", err)
+	}
+	paths := srv.swagger["paths"].(map[string]interface{})
+	op := paths["/v1/Old"].(map[string]interface{})["get"].(map[string]interface{})
+	if !reflect.DeepEqual(op["tags"], []string{"legacy"}) {
+		t.Fatalf("expected tags [legacy], got %v", op["tags"])
+	}
+	if op["deprecated"] != true {
+		t.Fatalf("expected deprecated operation, got %v", op["deprecated"])
+	}
+	if op["description"] != "Superseded by New" {
+		t.Fatalf("unexpected description %v", op["description"])
+	}
+}
+
+// TestUpdateSwaggerDocErrorResponses checks default and per-method error responses.
+func TestUpdateSwaggerDocErrorResponses(t *testing.T) {
+	srv := &Server{}
+	if err := updateSwaggerDoc(srv, &TestService{}, "/v1"); err != nil {
+		t.Fatalf("updateSwaggerDoc returned error: %v", err)
+	}
+	paths := srv.swagger["paths"].(map[string]interface{})
+	op := paths["/v1/Create"].(map[string]interface{})["post"].(map[string]interface{})
+	responses := op["responses"].(map[string]interface{})
+	for _, status := range []string{"200", "400", "422", "500"} {
+		if _, ok := responses[status]; !ok {
+			t.Fatalf("expected %s response, got %v", status, responses)
+		}
+	}
+
+	if err := updateSwaggerDoc(srv, taggedService{}, "/v1"); err != nil {
+		t.Fatalf("updateSwaggerDoc returned error: %v", err)
+	}
+	op = paths["/v1/Old"].(map[string]interface{})["get"].(map[string]interface{})
+	responses = op["responses"].(map[string]interface{})
+	if len(responses) != 2 || responses["404"] == nil {
+		t.Fatalf("expected 200 and 404 responses, got %v", responses)
+	}
+}
+
+// profileInput has an optional, nullable field.
+type profileInput struct {
+	Name     string  `json:"name" validate:"required"`
+	Nickname *string `json:"nickname"`
+}
+
+type profileService struct{}
+
+func (s profileService) Update(in profileInput) (string, error) { return in.Name, nil }
+func (s profileService) RegisterMethods() []MethodInfo {
+	return []MethodInfo{{
+		Name:       "Update",
+		HTTPMethod: "POST",
+		InputType:  reflect.TypeOf(profileInput{}),
+		OutputType: reflect.TypeOf(""),
+		Func:       reflect.ValueOf(s).MethodByName("Update"),
+	}}
+}
+
+// nicknameSchema returns the schema of profileInput.Nickname in srv's doc.
+func nicknameSchema(t *testing.T, srv *Server, path string) map[string]interface{} {
+	t.Helper()
+	op := srv.swagger["paths"].(map[string]interface{})[path].(map[string]interface{})["post"].(map[string]interface{})
+	content := op["requestBody"].(map[string]interface{})["content"].(map[string]interface{})
+	schema := content["application/json"].(map[string]interface{})["schema"].(map[string]interface{})
+	return schema["properties"].(map[string]interface{})["nickname"].(map[string]interface{})
+}
+
+// TestSetOpenAPIVersion verifies the document version, schema dialect and
+// nullable style follow the chosen OpenAPI version.
+func TestSetOpenAPIVersion(t *testing.T) {
+	c, _ := config.New(config.WithDefaultStruct(ServerConfig{OtelEnabled: false, Port: 8080}))
+	srv, _ := NewServer(c)
+	if err := srv.RegisterService(profileService{}, WithPathPrefix("/v1")); err != nil {
+		t.Fatalf("register service failed: %v", err)
+	}
+	if got := srv.swagger["openapi"]; got != OpenAPIVersion30 {
+		t.Fatalf("expected default version %s, got %v", OpenAPIVersion30, got)
+	}
+	if got := nicknameSchema(t, srv, "/v1/Update"); !reflect.DeepEqual(got, map[string]interface{}{"type": "string", "nullable": true}) {
+		t.Fatalf("unexpected 3.0 nullable schema: %v", got)
+	}
+
+	if err := srv.SetOpenAPIVersion(OpenAPIVersion31); err != nil {
+		t.Fatalf("set version failed: %v", err)
+	}
+	if got := srv.swagger["openapi"]; got != "3.1.0" {
+		t.Fatalf("expected version 3.1.0, got %v", got)
+	}
+	if got := srv.swagger["jsonSchemaDialect"]; got != jsonSchemaDialect31 {
+		t.Fatalf("expected schema dialect, got %v", got)
+	}
+	want31 := map[string]interface{}{"type": []string{"string", "null"}}
+	if got := nicknameSchema(t, srv, "/v1/Update"); !reflect.DeepEqual(got, want31) {
+		t.Fatalf("unexpected 3.1 nullable schema for existing endpoint: %v", got)
+	}
+	// Endpoints registered afterwards use the 3.1 style too
+	if err := srv.RegisterService(profileService{}, WithPathPrefix("/v2")); err != nil {
+		t.Fatalf("register service failed: %v", err)
+	}
+	if got := nicknameSchema(t, srv, "/v2/Update"); !reflect.DeepEqual(got, want31) {
+		t.Fatalf("unexpected 3.1 nullable schema for new endpoint: %v", got)
+	}
+
+	if err := srv.SetOpenAPIVersion(OpenAPIVersion30); err != nil {
+		t.Fatalf("set version failed: %v", err)
+	}
+	if _, ok := srv.swagger["jsonSchemaDialect"]; ok {
+		t.Fatal("expected no schema dialect for 3.0")
+	}
+	if got := nicknameSchema(t, srv, "/v2/Update"); !reflect.DeepEqual(got, map[string]interface{}{"type": "string", "nullable": true}) {
+		t.Fatalf("unexpected schema after switching back to 3.0: %v", got)
+	}
+
+	if err := srv.SetOpenAPIVersion("2.0"); err == nil {
+		t.Fatal("expected error for unsupported version")
+	}
+}
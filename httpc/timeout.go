@@ -0,0 +1,149 @@
+package httpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/T-Prohmpossadhorn/go-core/logger"
+	"github.com/gin-gonic/gin"
+)
+
+// timeoutBody is the response sent when a request exceeds its deadline.
+const timeoutBody = `{"error":"request timed out"}`
+
+// timeoutMiddleware bounds each request to timeout. The request context
+// carries the deadline, and if the handler has not started writing a response
+// by then the client receives 504 Gateway Timeout and later writes from the
+// handler are discarded. The handler keeps running in the background until it
+// returns; handlers that honor the request context stop early. Requests for
+// which exempt returns true, such as event streams and WebSocket upgrades,
+// are passed through without a deadline.
+func timeoutMiddleware(timeout time.Duration, exempt func(*gin.Context) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if exempt(c) {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		tw := &timeoutWriter{ResponseWriter: c.Writer}
+		c.Writer = tw
+
+		// Run the rest of the chain in a goroutine and forward any panic so
+		// gin.Recovery still handles it.
+		done := make(chan interface{}, 1)
+		go func() {
+			defer func() { done <- recover() }()
+			c.Next()
+		}()
+
+		select {
+		case p := <-done:
+			if p != nil {
+				panic(p)
+			}
+			return
+		case <-ctx.Done():
+		}
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && tw.timeout() {
+			logger.WarnContext(ctx, "Request timed out",
+				logger.String("request_id", RequestIDFromContext(ctx)),
+				logger.String("path", c.Request.URL.Path),
+				logger.Int("timeout_ms", int(timeout.Milliseconds())),
+			)
+		}
+		// gin reuses the context once this handler returns, so wait for the
+		// handler to finish with it
+		if p := <-done; p != nil {
+			panic(p)
+		}
+	}
+}
+
+// timeoutWriter passes writes through to the underlying writer until the
+// request times out, then discards them.
+type timeoutWriter struct {
+	gin.ResponseWriter
+	mu       sync.Mutex
+	timedOut bool
+	header   http.Header
+}
+
+// timeout marks the writer as timed out and writes the 504 response. It
+// returns false when the handler had already started the response.
+func (w *timeoutWriter) timeout() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+	w.header = http.Header{}
+	if w.ResponseWriter.Written() {
+		return false
+	}
+	h := w.ResponseWriter.Header()
+	h.Set("Content-Type", "application/json; charset=utf-8")
+	h.Set("Content-Length", strconv.Itoa(len(timeoutBody)))
+	w.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+	_, _ = w.ResponseWriter.WriteString(timeoutBody)
+	w.ResponseWriter.Flush()
+	return true
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return w.header
+	}
+	return w.ResponseWriter.Header()
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *timeoutWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return
+	}
+	w.ResponseWriter.Flush()
+}
@@ -0,0 +1,142 @@
+package httpc
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/T-Prohmpossadhorn/go-core/config"
+	"github.com/stretchr/testify/require"
+)
+
+// testPKI is a CA with a server and a client certificate signed by it.
+type testPKI struct {
+	caFile, certFile, keyFile string
+	caPool                    *x509.CertPool
+	serverCert                tls.Certificate
+}
+
+// newTestPKI generates a CA, a server certificate for 127.0.0.1 and a client
+// certificate, writing the CA and client files to dir.
+func newTestPKI(t *testing.T, dir string) testPKI {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "httpc-test-ca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage) ([]byte, *ecdsa.PrivateKey) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Minute),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		return der, key
+	}
+	encodeKey := func(key *ecdsa.PrivateKey) []byte {
+		der, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	}
+
+	serverDER, serverKey := issue(2, "httpc-test-server", x509.ExtKeyUsageServerAuth)
+	serverCert, err := tls.X509KeyPair(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: serverDER}), encodeKey(serverKey))
+	require.NoError(t, err)
+	clientDER, clientKey := issue(3, "httpc-test-client", x509.ExtKeyUsageClientAuth)
+
+	pki := testPKI{
+		caFile:     filepath.Join(dir, "ca.pem"),
+		certFile:   filepath.Join(dir, "client.pem"),
+		keyFile:    filepath.Join(dir, "client-key.pem"),
+		caPool:     x509.NewCertPool(),
+		serverCert: serverCert,
+	}
+	pki.caPool.AddCert(caCert)
+	require.NoError(t, os.WriteFile(pki.caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0600))
+	require.NoError(t, os.WriteFile(pki.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: clientDER}), 0600))
+	require.NoError(t, os.WriteFile(pki.keyFile, encodeKey(clientKey), 0600))
+	return pki
+}
+
+func TestHTTPClientMutualTLS(t *testing.T) {
+	pki := newTestPKI(t, t.TempDir())
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`"hello ` + r.TLS.PeerCertificates[0].Subject.CommonName + `"`))
+	}))
+	ts.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pki.serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pki.caPool,
+	}
+	ts.StartTLS()
+	defer ts.Close()
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"http_client_tls_cert_file":   pki.certFile,
+		"http_client_tls_key_file":    pki.keyFile,
+		"http_client_ca_file":         pki.caFile,
+		"http_client_max_retries":     0,
+		"http_client_disable_backoff": true,
+	}))
+	require.NoError(t, err)
+	client, err := NewHTTPClient(cfg)
+	require.NoError(t, err)
+
+	var out string
+	require.NoError(t, client.CallContext(context.Background(), "GET", ts.URL, nil, &out))
+	require.Equal(t, "hello httpc-test-client", out)
+
+	// Without a client certificate the handshake is rejected
+	cfg, err = config.New(config.WithDefault(map[string]interface{}{
+		"http_client_ca_file":         pki.caFile,
+		"http_client_max_retries":     0,
+		"http_client_disable_backoff": true,
+	}))
+	require.NoError(t, err)
+	client, err = NewHTTPClient(cfg)
+	require.NoError(t, err)
+	require.Error(t, client.CallContext(context.Background(), "GET", ts.URL, nil, &out))
+}
+
+func TestHTTPClientTLSMissingFile(t *testing.T) {
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"http_client_ca_file": filepath.Join(t.TempDir(), "missing.pem"),
+	}))
+	require.NoError(t, err)
+	_, err = NewHTTPClient(cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "failed to configure TLS")
+}
@@ -1,25 +1,43 @@
 package httpc
 
 import (
+	"context"
 	"net/http"
 	"reflect"
 	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
 )
 
 // MethodInfo represents a service method's metadata
 type MethodInfo struct {
-	Name       string
-	HTTPMethod string
-	InputType  reflect.Type
-	OutputType reflect.Type
-	Func       reflect.Value // Stores method function
+	Name           string
+	HTTPMethod     string
+	InputType      reflect.Type
+	OutputType     reflect.Type
+	Func           reflect.Value  // Stores method function
+	Description    string         // Longer description shown in the OpenAPI docs
+	Tags           []string       // OpenAPI tags used to group operations
+	Deprecated     bool           // Marks the operation as deprecated in the OpenAPI docs
+	ErrorResponses map[int]string // Documented error responses by status code; nil documents 400, 422 and 500
+	ContentType    string         // Request body content type; a non-JSON type with a string or []byte InputType receives the raw body
+	Path           string         // Route relative to the service prefix, e.g. "users/:id"; defaults to Name
+}
+
+// RouteInfo describes an endpoint wired up by RegisterService
+type RouteInfo struct {
+	Method      string // HTTP method, e.g. "GET"
+	Path        string // Full route including the service prefix, e.g. "/v1/users/:id"
+	OperationID string // OpenAPI operationId, the service method name
 }
 
 // ServiceOption configures service registration
 type ServiceOption func(*serviceConfig)
 
 type serviceConfig struct {
-	prefix string
+	prefix     string
+	middleware map[string][]gin.HandlerFunc
 }
 
 // WithPathPrefix sets a custom path prefix for endpoints
@@ -29,6 +47,96 @@ func WithPathPrefix(prefix string) ServiceOption {
 	}
 }
 
+// WithMethodMiddleware runs middleware before the handler of the service
+// method with the given name, e.g. to require authentication on Create while
+// leaving other methods public. Repeated calls for the same method append.
+func WithMethodMiddleware(method string, middleware ...gin.HandlerFunc) ServiceOption {
+	return func(s *serviceConfig) {
+		if s.middleware == nil {
+			s.middleware = map[string][]gin.HandlerFunc{}
+		}
+		s.middleware[method] = append(s.middleware[method], middleware...)
+	}
+}
+
+// ClientOption configures an HTTPClient
+type ClientOption func(*HTTPClient)
+
+// CompleteFunc receives the outcome of a request sent by an HTTPClient: the
+// final status code (0 when no response was received), the time taken
+// including retries and backoff, and the error, if any
+type CompleteFunc func(method, url string, status int, dur time.Duration, err error)
+
+// WithOnComplete registers fn to be called after every request sent through
+// the client, for recording latency metrics without OpenTelemetry
+func WithOnComplete(fn CompleteFunc) ClientOption {
+	return func(h *HTTPClient) {
+		h.onComplete = fn
+	}
+}
+
+// WithStrictJSON makes the client reject JSON responses containing fields
+// that the output type does not declare, for services with strict contracts
+func WithStrictJSON() ClientOption {
+	return func(h *HTTPClient) {
+		h.strictJSON = true
+	}
+}
+
+// CallOption configures a single HTTPClient call
+type CallOption func(*callConfig)
+
+type callConfig struct {
+	headers   map[string]string
+	allowBody bool
+}
+
+// WithHeader sets a request header for a single call, overriding any default header
+func WithHeader(key, value string) CallOption {
+	return func(c *callConfig) {
+		if c.headers == nil {
+			c.headers = map[string]string{}
+		}
+		c.headers[key] = value
+	}
+}
+
+// WithRequestBody sends the input of a GET or HEAD call as the request body.
+// By default such calls send no body or Content-Type, since servers and
+// proxies may reject or drop it.
+func WithRequestBody() CallOption {
+	return func(c *callConfig) {
+		c.allowBody = true
+	}
+}
+
+// sendsBody reports whether a request with the given method carries the
+// call's input as its body.
+func (c *callConfig) sendsBody(method string) bool {
+	return c.allowBody || (method != http.MethodGet && method != http.MethodHead)
+}
+
+// RequestIDHeader is the header used to propagate request ids
+const RequestIDHeader = "X-Request-ID"
+
+// IdempotencyKeyHeader is the header carrying the idempotency key of a POST or
+// PATCH request, so servers can recognize a retry of a request they already
+// processed
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type requestIDKey struct{}
+
+// WithRequestID returns a copy of ctx carrying the given request id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request id stored in ctx, or an empty string
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
 // isValidHTTPMethod checks if the given method is a valid HTTP method
 func isValidHTTPMethod(method string) bool {
 	validMethods := []string{
@@ -49,3 +157,14 @@ func isValidHTTPMethod(method string) bool {
 	}
 	return false
 }
+
+// isRawBody reports whether m receives the request body unparsed: its
+// ContentType is set to something other than JSON and its input is a string
+// or []byte.
+func isRawBody(m MethodInfo) bool {
+	if m.ContentType == "" || m.ContentType == "application/json" || m.InputType == nil {
+		return false
+	}
+	t := m.InputType
+	return t.Kind() == reflect.String || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8)
+}
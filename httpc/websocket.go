@@ -0,0 +1,55 @@
+package httpc
+
+import (
+	"net/http"
+
+	"github.com/T-Prohmpossadhorn/go-core/logger"
+	"github.com/T-Prohmpossadhorn/go-core/otel"
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	otelglobal "go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// WebSocketHandler serves a single upgraded WebSocket connection. The
+// connection is closed when the handler returns.
+type WebSocketHandler func(conn *websocket.Conn)
+
+var upgrader = websocket.Upgrader{}
+
+// RegisterWebSocket registers a GET endpoint at path that upgrades requests to
+// WebSocket connections and passes them to handler. When OpenTelemetry is
+// enabled a server span covering the connection is started on connect,
+// continuing any trace propagated in the upgrade request headers.
+func (s *Server) RegisterWebSocket(path string, handler WebSocketHandler) {
+	s.markStream(http.MethodGet, path)
+	s.engine.GET(path, func(c *gin.Context) {
+		ctx := c.Request.Context()
+		reqIDField := logger.String("request_id", RequestIDFromContext(ctx))
+
+		if s.otelEnabled {
+			ctx = otelglobal.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(c.Request.Header))
+			var span oteltrace.Span
+			ctx, span = otel.StartSpanWithOptions(ctx, "httpc", "WebSocket "+path,
+				otel.WithSpanKind(oteltrace.SpanKindServer),
+				otel.WithAttributes(attribute.String("http.route", path)),
+			)
+			defer span.End()
+		}
+
+		conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+		if err != nil {
+			// Upgrade has already written an error response
+			logger.ErrorContext(ctx, "WebSocket upgrade failed", reqIDField, logger.ErrField(err))
+			return
+		}
+		defer conn.Close()
+
+		logger.InfoContext(ctx, "WebSocket connected", reqIDField, logger.String("path", path))
+		handler(conn)
+		logger.InfoContext(ctx, "WebSocket disconnected", reqIDField, logger.String("path", path))
+	})
+	logger.Info("Registered WebSocket endpoint", logger.String("path", path))
+}
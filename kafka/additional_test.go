@@ -3,7 +3,12 @@ package kafka
 import (
 	"context"
 	"errors"
+	"io"
+	"net"
+	"reflect"
+	"syscall"
 	"testing"
+	"time"
 
 	"github.com/T-Prohmpossadhorn/go-core/config"
 	kafka_go "github.com/segmentio/kafka-go"
@@ -51,3 +56,166 @@ func TestConsumeReaderError(t *testing.T) {
 		t.Fatal("expected channel to close on error")
 	}
 }
+
+// rebalanceReader returns msgs and then fails with a rebalance error.
+type rebalanceReader struct {
+	msgs   []kafka_go.Message
+	closed bool
+}
+
+func (r *rebalanceReader) ReadMessage(context.Context) (kafka_go.Message, error) {
+	if len(r.msgs) > 0 {
+		m := r.msgs[0]
+		r.msgs = r.msgs[1:]
+		return m, nil
+	}
+	return kafka_go.Message{}, kafka_go.RebalanceInProgress
+}
+func (r *rebalanceReader) Close() error { r.closed = true; return nil }
+
+// partitionReader serves a partition log from its current offset, which
+// starts at the first message like a new reader without a consumer group.
+type partitionReader struct {
+	log    []kafka_go.Message
+	offset int64
+	reads  int
+}
+
+func (p *partitionReader) ReadMessage(context.Context) (kafka_go.Message, error) {
+	if p.offset >= int64(len(p.log)) {
+		return kafka_go.Message{}, io.EOF
+	}
+	m := p.log[p.offset]
+	p.offset++
+	p.reads++
+	return m, nil
+}
+func (p *partitionReader) Close() error                                 { return nil }
+func (p *partitionReader) SetOffset(offset int64) error                 { p.offset = offset; return nil }
+func (p *partitionReader) SetOffsetAt(context.Context, time.Time) error { return nil }
+
+// TestConsumeRecreatesReaderOnTransientError verifies consumption resumes
+// after a rebalance at the offset following the last delivered message.
+func TestConsumeRecreatesReaderOnTransientError(t *testing.T) {
+	origBackoff := readerRetryBackoff
+	readerRetryBackoff = time.Millisecond
+	defer func() { readerRetryBackoff = origBackoff }()
+
+	log := []kafka_go.Message{
+		{Offset: 0, Value: []byte("m0")},
+		{Offset: 1, Value: []byte("m1")},
+		{Offset: 2, Value: []byte("m2")},
+	}
+	first := &rebalanceReader{msgs: log[:2]}
+	second := &partitionReader{log: log}
+	readers := []reader{first, second}
+	origReader := readerFactoryFunc
+	readerFactoryFunc = func([]string, string, Config) reader {
+		r := readers[0]
+		readers = readers[1:]
+		return r
+	}
+	defer func() { readerFactoryFunc = origReader }()
+
+	cfg, _ := config.New()
+	k, _ := New(cfg)
+	ch, err := k.Consume(context.Background(), "t")
+	if err != nil {
+		t.Fatalf("consume returned error: %v", err)
+	}
+	var got []string
+	for msg := range ch {
+		got = append(got, string(msg))
+	}
+	if want := []string{"m0", "m1", "m2"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if !first.closed {
+		t.Fatal("expected failed reader to be closed")
+	}
+}
+
+// brokenWriter fails every write with a connection error.
+type brokenWriter struct{ closed bool }
+
+func (b *brokenWriter) WriteMessages(context.Context, ...kafka_go.Message) error {
+	return &net.OpError{Op: "write", Net: "tcp", Err: syscall.ECONNRESET}
+}
+func (b *brokenWriter) Close() error { b.closed = true; return nil }
+
+// TestPublishRecreatesWriterAfterConnectionError verifies a broken writer is replaced on the next publish.
+func TestPublishRecreatesWriterAfterConnectionError(t *testing.T) {
+	broken := &brokenWriter{}
+	fresh := &noWriter{}
+	writers := []writer{broken, fresh}
+	created := 0
+	origWriter := writerFactoryFunc
+	writerFactoryFunc = func([]string, string, Config) writer {
+		w := writers[created]
+		created++
+		return w
+	}
+	defer func() { writerFactoryFunc = origWriter }()
+
+	cfg, _ := config.New()
+	k, _ := New(cfg)
+	if err := k.Publish(context.Background(), "t", []byte("x")); err == nil {
+		t.Fatal("expected connection error")
+	}
+	if !broken.closed {
+		t.Fatal("expected broken writer to be closed")
+	}
+	if err := k.Publish(context.Background(), "t", []byte("x")); err != nil {
+		t.Fatalf("publish with fresh writer failed: %v", err)
+	}
+	if created != 2 {
+		t.Fatalf("expected 2 writers to be created, got %d", created)
+	}
+}
+
+// TestPublishKeepsWriterOnMessageError verifies non-connection errors keep the cached writer.
+func TestPublishKeepsWriterOnMessageError(t *testing.T) {
+	created := 0
+	origWriter := writerFactoryFunc
+	writerFactoryFunc = func([]string, string, Config) writer {
+		created++
+		return &errWriter{}
+	}
+	defer func() { writerFactoryFunc = origWriter }()
+
+	cfg, _ := config.New()
+	k, _ := New(cfg)
+	_ = k.Publish(context.Background(), "t", []byte("x"))
+	_ = k.Publish(context.Background(), "t", []byte("x"))
+	if created != 1 {
+		t.Fatalf("expected writer to be reused, created %d", created)
+	}
+}
+
+// TestClientID verifies kafka_client_id reaches the writer's transport and
+// the reader's dialer.
+func TestClientID(t *testing.T) {
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{"kafka_client_id": "orders-svc"}))
+	k, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new returned error: %v", err)
+	}
+
+	w, ok := writerFactoryFunc(k.brokers, "t", k.cfg).(*kafka_go.Writer)
+	if !ok {
+		t.Fatal("expected *kafka_go.Writer")
+	}
+	defer w.Close()
+	if id := w.Transport.(*kafka_go.Transport).ClientID; id != "orders-svc" {
+		t.Fatalf("expected writer client id orders-svc, got %q", id)
+	}
+
+	r, ok := readerFactoryFunc(k.brokers, "t", k.cfg).(*kafka_go.Reader)
+	if !ok {
+		t.Fatal("expected *kafka_go.Reader")
+	}
+	defer r.Close()
+	if id := r.Config().Dialer.ClientID; id != "orders-svc" {
+		t.Fatalf("expected reader client id orders-svc, got %q", id)
+	}
+}
@@ -4,15 +4,22 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
+	"time"
 
+	"github.com/google/uuid"
 	kafka_go "github.com/segmentio/kafka-go"
 	"github.com/segmentio/kafka-go/sasl/plain"
 
-	otelglobal "go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 
 	"github.com/T-Prohmpossadhorn/go-core/config"
 	"github.com/T-Prohmpossadhorn/go-core/logger"
@@ -28,8 +35,47 @@ type Config struct {
 	EnableTLS   bool   `mapstructure:"kafka_enable_tls" default:"false"`
 	Username    string `mapstructure:"kafka_username" default:""`
 	Password    string `mapstructure:"kafka_password" default:""`
+	// ClientID identifies the client to the brokers in their logs and
+	// metrics. Empty leaves kafka-go's defaults in place.
+	ClientID string `mapstructure:"kafka_client_id" default:""`
+	// MaxMessageBytes rejects messages whose key, value and headers add up
+	// to more than this many bytes before they are written. Zero disables
+	// the check.
+	MaxMessageBytes int `mapstructure:"kafka_max_message_bytes" default:"1048576"`
+	// WriteTimeoutMs bounds each write whose context has no deadline of its
+	// own. Zero leaves such writes unbounded.
+	WriteTimeoutMs int `mapstructure:"kafka_write_timeout_ms" default:"0"`
+	// TransactionalID identifies the transactional producer used by BeginTx,
+	// PublishTx and CommitTx. Empty disables transactions.
+	TransactionalID string `mapstructure:"kafka_transactional_id" default:""`
 }
 
+// DefaultMaxMessageBytes is the default kafka_max_message_bytes, matching the
+// kafka-go writer's batch limit and the broker's default max.message.bytes.
+const DefaultMaxMessageBytes = 1048576
+
+// ErrMessageTooLarge is returned, wrapped, when a message exceeds
+// kafka_max_message_bytes. Such messages are rejected before any write.
+var ErrMessageTooLarge = errors.New("message too large")
+
+// Header keys used by Request to correlate requests with their replies.
+const (
+	CorrelationIDHeader = "correlation_id"
+	ReplyTopicHeader    = "reply_topic"
+)
+
+// Header keys and topic suffixes used by ConsumeWithRetry.
+const (
+	// RetryAttemptHeader counts how many times a message has been retried.
+	RetryAttemptHeader = "retry_attempt"
+	// RetryErrorHeader holds the handler error of the last failed attempt.
+	RetryErrorHeader = "retry_error"
+	// RetryTopicSuffix and DeadLetterTopicSuffix are appended to the consumed
+	// topic to name its retry and dead-letter topics.
+	RetryTopicSuffix      = ".retry"
+	DeadLetterTopicSuffix = ".dlq"
+)
+
 // Kafka wraps kafka-go writers and readers to talk to a real Kafka broker.
 // writer defines the minimal interface needed from kafka-go writers.
 type writer interface {
@@ -37,6 +83,25 @@ type writer interface {
 	Close() error
 }
 
+// writerStatser is implemented by writers that report statistics, such as
+// *kafka_go.Writer.
+type writerStatser interface {
+	Stats() kafka_go.WriterStats
+}
+
+// readerStatser is implemented by readers that report statistics, such as
+// *kafka_go.Reader.
+type readerStatser interface {
+	Stats() kafka_go.ReaderStats
+}
+
+// seeker is implemented by readers that can be positioned explicitly, such as
+// *kafka_go.Reader without a consumer group.
+type seeker interface {
+	SetOffset(offset int64) error
+	SetOffsetAt(ctx context.Context, t time.Time) error
+}
+
 // reader defines the minimal interface needed from kafka-go readers.
 type reader interface {
 	ReadMessage(context.Context) (kafka_go.Message, error)
@@ -45,7 +110,18 @@ type reader interface {
 
 // writerFactoryFunc creates a writer for a topic.
 var writerFactoryFunc = func(brokers []string, topic string, cfg Config) writer {
-	t := &kafka_go.Transport{}
+	return &kafka_go.Writer{
+		Addr:      kafka_go.TCP(brokers...),
+		Topic:     topic,
+		Balancer:  &kafka_go.LeastBytes{},
+		Transport: newTransport(cfg),
+	}
+}
+
+// newTransport returns a transport applying the client id, TLS and SASL
+// settings of cfg.
+func newTransport(cfg Config) *kafka_go.Transport {
+	t := &kafka_go.Transport{ClientID: cfg.ClientID}
 	if cfg.EnableTLS {
 		t.TLS = &tls.Config{}
 	}
@@ -55,17 +131,60 @@ var writerFactoryFunc = func(brokers []string, topic string, cfg Config) writer
 			Password: cfg.Password,
 		}
 	}
-	return &kafka_go.Writer{
-		Addr:      kafka_go.TCP(brokers...),
-		Topic:     topic,
-		Balancer:  &kafka_go.LeastBytes{},
-		Transport: t,
-	}
+	return t
 }
 
 // readerFactoryFunc creates a reader for a topic.
 var readerFactoryFunc = func(brokers []string, topic string, cfg Config) reader {
-	dialer := &kafka_go.Dialer{}
+	return kafka_go.NewReader(kafka_go.ReaderConfig{
+		Brokers:        brokers,
+		Topic:          topic,
+		GroupID:        "",
+		Dialer:         newDialer(cfg),
+		IsolationLevel: kafka_go.ReadCommitted,
+	})
+}
+
+// partitionReaderFactoryFunc creates a reader for one partition of a topic
+// outside any consumer group.
+var partitionReaderFactoryFunc = func(brokers []string, topic string, partition int, cfg Config) reader {
+	return kafka_go.NewReader(kafka_go.ReaderConfig{
+		Brokers:        brokers,
+		Topic:          topic,
+		Partition:      partition,
+		Dialer:         newDialer(cfg),
+		IsolationLevel: kafka_go.ReadCommitted,
+	})
+}
+
+// endOffsetsFunc returns, for every partition of a topic, the offset the next
+// message written to it will get.
+var endOffsetsFunc = func(ctx context.Context, brokers []string, topic string, cfg Config) (map[int]int64, error) {
+	dialer := newDialer(cfg)
+	partitions, err := dialer.LookupPartitions(ctx, "tcp", brokers[0], topic)
+	if err != nil {
+		return nil, err
+	}
+	offsets := make(map[int]int64, len(partitions))
+	for _, p := range partitions {
+		conn, err := dialer.DialLeader(ctx, "tcp", brokers[0], topic, p.ID)
+		if err != nil {
+			return nil, err
+		}
+		offset, err := conn.ReadLastOffset()
+		_ = conn.Close()
+		if err != nil {
+			return nil, err
+		}
+		offsets[p.ID] = offset
+	}
+	return offsets, nil
+}
+
+// newDialer returns a dialer applying the client id, TLS and SASL settings of
+// cfg.
+func newDialer(cfg Config) *kafka_go.Dialer {
+	dialer := &kafka_go.Dialer{ClientID: cfg.ClientID}
 	if cfg.EnableTLS {
 		dialer.TLS = &tls.Config{}
 	}
@@ -75,22 +194,33 @@ var readerFactoryFunc = func(brokers []string, topic string, cfg Config) reader
 			Password: cfg.Password,
 		}
 	}
-	return kafka_go.NewReader(kafka_go.ReaderConfig{
-		Brokers: brokers,
-		Topic:   topic,
-		GroupID: "",
-		Dialer:  dialer,
-	})
+	return dialer
 }
 
+// Backoff bounds used when a consumer recreates its reader after a transient
+// error such as a consumer group rebalance.
+var (
+	readerRetryBackoff    = 100 * time.Millisecond
+	readerRetryBackoffMax = 5 * time.Second
+)
+
 // Kafka wraps kafka-go writers and readers to talk to a real Kafka broker.
 type Kafka struct {
 	mu         sync.RWMutex
 	writers    map[string]writer
 	readers    map[string]reader
+	seekers    map[reader]struct{}
 	brokers    []string
 	cfg        Config
 	tracerName string
+	wg         sync.WaitGroup
+	paused     map[string]chan struct{}
+
+	// txMu guards the transactional producer and whether a transaction is
+	// open.
+	txMu sync.Mutex
+	tx   txProducer
+	inTx bool
 }
 
 // New creates a new Kafka instance with the provided config.
@@ -102,12 +232,24 @@ func New(c *config.Config) (*Kafka, error) {
 		EnableTLS:   c.GetBool("kafka_enable_tls"),
 		Username:    c.GetStringWithDefault("kafka_username", ""),
 		Password:    c.GetStringWithDefault("kafka_password", ""),
+		ClientID:    c.GetStringWithDefault("kafka_client_id", ""),
+	}
+	cfg.TransactionalID = c.GetStringWithDefault("kafka_transactional_id", "")
+	cfg.WriteTimeoutMs = c.GetIntWithDefault("kafka_write_timeout_ms", 0)
+	if cfg.WriteTimeoutMs < 0 {
+		return nil, fmt.Errorf("invalid kafka_write_timeout_ms: %d", cfg.WriteTimeoutMs)
+	}
+	cfg.MaxMessageBytes = c.GetIntWithDefault("kafka_max_message_bytes", DefaultMaxMessageBytes)
+	if cfg.MaxMessageBytes < 0 {
+		return nil, fmt.Errorf("invalid kafka_max_message_bytes: %d", cfg.MaxMessageBytes)
 	}
 
 	brokers := strings.Split(cfg.Brokers, ",")
 	k := &Kafka{
 		writers:    make(map[string]writer),
 		readers:    make(map[string]reader),
+		seekers:    make(map[reader]struct{}),
+		paused:     make(map[string]chan struct{}),
 		brokers:    brokers,
 		cfg:        cfg,
 		tracerName: "kafka",
@@ -117,40 +259,265 @@ func New(c *config.Config) (*Kafka, error) {
 }
 
 // Publish sends a message to the specified topic.
-func (k *Kafka) Publish(ctx context.Context, topic string, body []byte) error {
+func (k *Kafka) Publish(ctx context.Context, topic string, body []byte) (err error) {
 	var span oteltrace.Span
 	if k.cfg.OtelEnabled {
-		ctx, span = otel.StartSpan(ctx, k.tracerName, "Publish")
-		defer span.End()
+		ctx, span = otel.StartSpanWithOptions(ctx, k.tracerName, "Publish", messagingSpanOptions(oteltrace.SpanKindProducer, topic)...)
+		defer func() { endSpan(span, err) }()
 	}
 	if ctx.Err() != nil {
 		return fmt.Errorf("publish canceled: %w", ctx.Err())
 	}
 
+	w := k.writer(topic)
+	err = k.writeMessages(ctx, w, kafka_go.Message{Value: body, Headers: k.traceHeaders(ctx)})
+	if err != nil {
+		k.discardBrokenWriter(ctx, topic, w, err)
+		return fmt.Errorf("write message: %w", err)
+	}
+	logger.InfoContext(ctx, "Message published", logger.String("topic", topic))
+	return nil
+}
+
+// PublishDefault sends a message to the topic configured by kafka_topic.
+func (k *Kafka) PublishDefault(ctx context.Context, body []byte) error {
+	return k.Publish(ctx, k.cfg.Topic, body)
+}
+
+// Request publishes body to requestTopic with a generated correlation id and
+// waits on replyTopic for a message carrying the same correlation id. The
+// reply topic is advertised in the ReplyTopicHeader header. Replies are read
+// from every partition of replyTopic outside any consumer group, starting at
+// the end offsets found before the request is published, so earlier messages
+// are never scanned. Request fails once ctx is done.
+func (k *Kafka) Request(ctx context.Context, requestTopic, replyTopic string, body []byte) (reply []byte, err error) {
+	var span oteltrace.Span
+	if k.cfg.OtelEnabled {
+		ctx, span = otel.StartSpanWithOptions(ctx, k.tracerName, "Request", messagingSpanOptions(oteltrace.SpanKindProducer, requestTopic)...)
+		defer func() { endSpan(span, err) }()
+	}
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("request canceled: %w", ctx.Err())
+	}
+
+	// Dedicated readers keep replies from being consumed by Consume callers.
+	readers, err := k.openReplyReaders(ctx, replyTopic)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, r := range readers {
+			_ = r.Close()
+		}
+	}()
+
+	correlationID := uuid.New().String()
+	headers := append(k.traceHeaders(ctx),
+		kafka_go.Header{Key: CorrelationIDHeader, Value: []byte(correlationID)},
+		kafka_go.Header{Key: ReplyTopicHeader, Value: []byte(replyTopic)},
+	)
+	w := k.writer(requestTopic)
+	err = k.writeMessages(ctx, w, kafka_go.Message{Value: body, Headers: headers})
+	if err != nil {
+		k.discardBrokenWriter(ctx, requestTopic, w, err)
+		return nil, fmt.Errorf("write request: %w", err)
+	}
+	logger.InfoContext(ctx, "Request published", logger.String("topic", requestTopic), logger.String("correlation_id", correlationID))
+
+	readCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	replies := make(chan []byte, 1)
+	errs := make(chan error, len(readers))
+	for _, r := range readers {
+		go func() {
+			for {
+				m, err := readMessage(readCtx, r)
+				if err != nil {
+					errs <- err
+					return
+				}
+				if hasCorrelationID(m, correlationID) {
+					select {
+					case replies <- m.Value:
+					case <-readCtx.Done():
+					}
+					return
+				}
+			}
+		}()
+	}
+	select {
+	case reply = <-replies:
+		logger.InfoContext(ctx, "Reply received", logger.String("topic", replyTopic), logger.String("correlation_id", correlationID))
+		return reply, nil
+	case err = <-errs:
+		return nil, fmt.Errorf("wait for reply: %w", err)
+	}
+}
+
+// openReplyReaders creates a reader for every partition of topic positioned
+// at the partition's current end offset.
+func (k *Kafka) openReplyReaders(ctx context.Context, topic string) ([]reader, error) {
+	offsets, err := endOffsetsFunc(ctx, k.brokers, topic, k.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up offsets of topic %s: %w", topic, err)
+	}
+	if len(offsets) == 0 {
+		return nil, fmt.Errorf("topic %s has no partitions", topic)
+	}
+	readers := make([]reader, 0, len(offsets))
+	for partition, offset := range offsets {
+		r := partitionReaderFactoryFunc(k.brokers, topic, partition, k.cfg)
+		s, ok := r.(seeker)
+		if !ok {
+			err = fmt.Errorf("reader for topic %s does not support seeking", topic)
+		} else if err = s.SetOffset(offset); err != nil {
+			err = fmt.Errorf("failed to seek topic %s partition %d to offset %d: %w", topic, partition, offset, err)
+		}
+		if err != nil {
+			_ = r.Close()
+			for _, r := range readers {
+				_ = r.Close()
+			}
+			return nil, err
+		}
+		readers = append(readers, r)
+	}
+	return readers, nil
+}
+
+// hasCorrelationID reports whether m carries correlation id id.
+func hasCorrelationID(m kafka_go.Message, id string) bool {
+	for _, h := range m.Headers {
+		if h.Key == CorrelationIDHeader && string(h.Value) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// writer returns the cached writer for topic, creating it on first use.
+func (k *Kafka) writer(topic string) writer {
 	k.mu.Lock()
+	defer k.mu.Unlock()
 	w, ok := k.writers[topic]
 	if !ok {
 		w = writerFactoryFunc(k.brokers, topic, k.cfg)
 		k.writers[topic] = w
 	}
+	return w
+}
+
+// writeMessages writes msgs with w, applying kafka_write_timeout_ms when ctx
+// carries no deadline so an unresponsive broker cannot block forever. Nothing
+// is written when a message exceeds kafka_max_message_bytes.
+func (k *Kafka) writeMessages(ctx context.Context, w writer, msgs ...kafka_go.Message) error {
+	if err := k.checkMessageSize(msgs...); err != nil {
+		return err
+	}
+	if _, ok := ctx.Deadline(); !ok && k.cfg.WriteTimeoutMs > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(k.cfg.WriteTimeoutMs)*time.Millisecond)
+		defer cancel()
+	}
+	return w.WriteMessages(ctx, msgs...)
+}
+
+// checkMessageSize returns an error wrapping ErrMessageTooLarge if any of msgs
+// exceeds kafka_max_message_bytes.
+func (k *Kafka) checkMessageSize(msgs ...kafka_go.Message) error {
+	if limit := k.cfg.MaxMessageBytes; limit > 0 {
+		for _, m := range msgs {
+			if size := messageSize(m); size > limit {
+				return fmt.Errorf("%w: %d bytes exceeds kafka_max_message_bytes %d", ErrMessageTooLarge, size, limit)
+			}
+		}
+	}
+	return nil
+}
+
+// messageSize returns the bytes of m's key, value and headers, excluding the
+// record framing added by the protocol.
+func messageSize(m kafka_go.Message) int {
+	size := len(m.Key) + len(m.Value)
+	for _, h := range m.Headers {
+		size += len(h.Key) + len(h.Value)
+	}
+	return size
+}
+
+// discardBrokenWriter closes w and removes it from the cache when err points
+// to a broken broker connection, so the next publish creates a fresh writer.
+func (k *Kafka) discardBrokenWriter(ctx context.Context, topic string, w writer, err error) {
+	if !isConnectionError(err) {
+		return
+	}
+	k.mu.Lock()
+	if k.writers[topic] == w {
+		delete(k.writers, topic)
+	}
 	k.mu.Unlock()
+	_ = w.Close()
+	logger.WarnContext(ctx, "Discarded writer after connection error", logger.String("topic", topic), logger.ErrField(err))
+}
 
-	var headers []kafka_go.Header
-	if k.cfg.OtelEnabled {
-		carrier := propagation.MapCarrier{}
-		otelglobal.GetTextMapPropagator().Inject(ctx, carrier)
-		headers = make([]kafka_go.Header, 0, len(carrier))
-		for k, v := range carrier {
-			headers = append(headers, kafka_go.Header{Key: k, Value: []byte(v)})
+// isConnectionError reports whether err indicates the connection to the
+// broker is broken rather than a problem with the message itself.
+func isConnectionError(err error) bool {
+	var werrs kafka_go.WriteErrors
+	if errors.As(err, &werrs) {
+		for _, werr := range werrs {
+			if werr != nil && isConnectionError(werr) {
+				return true
+			}
 		}
+		return false
+	}
+	var nerr net.Error
+	if errors.As(err, &nerr) {
+		return true
+	}
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// messagingSpanOptions returns the span kind and messaging attributes for a
+// span operating on destination.
+func messagingSpanOptions(kind oteltrace.SpanKind, destination string) []otel.SpanOption {
+	return []otel.SpanOption{
+		otel.WithSpanKind(kind),
+		otel.WithAttributes(
+			attribute.String("messaging.system", "kafka"),
+			attribute.String("messaging.destination.name", destination),
+		),
 	}
+}
 
-	err := w.WriteMessages(ctx, kafka_go.Message{Value: body, Headers: headers})
+// endSpan records err on span, setting its status to Error, and ends it.
+func endSpan(span oteltrace.Span, err error) {
 	if err != nil {
-		return fmt.Errorf("write message: %w", err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
 	}
-	logger.InfoContext(ctx, "Message published", logger.String("topic", topic))
-	return nil
+	span.End()
+}
+
+// traceHeaders returns message headers carrying the trace context of ctx when
+// tracing is enabled.
+func (k *Kafka) traceHeaders(ctx context.Context) []kafka_go.Header {
+	if !k.cfg.OtelEnabled {
+		return nil
+	}
+	carrier := otel.InjectHeaders(ctx)
+	headers := make([]kafka_go.Header, 0, len(carrier))
+	for key, v := range carrier {
+		headers = append(headers, kafka_go.Header{Key: key, Value: []byte(v)})
+	}
+	return headers
 }
 
 // Consume returns a channel to receive messages from the specified topic.
@@ -161,38 +528,455 @@ func (k *Kafka) Consume(ctx context.Context, topic string) (<-chan []byte, error
 		defer span.End()
 	}
 
+	r := k.reader(topic)
+	out := make(chan []byte)
+	k.wg.Add(1)
+	go func() {
+		defer k.wg.Done()
+		defer close(out)
+		k.consumeLoop(ctx, topic, r, sendValue(ctx, out), func(old reader, next int64) (reader, error) {
+			return k.recreateReader(topic, old, next)
+		})
+	}()
+	logger.InfoContext(ctx, "Consumer registered", logger.String("topic", topic))
+	return out, nil
+}
+
+// reader returns the cached reader for topic, creating it on first use.
+func (k *Kafka) reader(topic string) reader {
 	k.mu.Lock()
+	defer k.mu.Unlock()
 	r, ok := k.readers[topic]
 	if !ok {
 		r = readerFactoryFunc(k.brokers, topic, k.cfg)
 		k.readers[topic] = r
 	}
-	k.mu.Unlock()
+	return r
+}
 
-	out := make(chan []byte)
-	go func() {
-		defer close(out)
-		for {
-			m, err := r.ReadMessage(ctx)
-			if err != nil {
-				return
+// sendValue returns a consumeLoop callback that sends message values to out.
+func sendValue(ctx context.Context, out chan<- []byte) func(kafka_go.Message) bool {
+	return func(m kafka_go.Message) bool {
+		select {
+		case out <- m.Value:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// consumeLoop passes messages read from r to deliver until ctx is canceled,
+// deliver returns false or a
+// non-retryable error occurs, and returns the reader in use at that point, or
+// nil if reopen failed. Transient errors replace the reader with the one
+// returned by reopen after an exponential backoff; next is the offset after the
+// last message read, or -1 if none has been read yet.
+func (k *Kafka) consumeLoop(ctx context.Context, topic string, r reader, deliver func(kafka_go.Message) bool, reopen func(old reader, next int64) (reader, error)) reader {
+	backoff := readerRetryBackoff
+	next := int64(-1)
+	for {
+		if !k.waitResumed(ctx, topic) {
+			return r
+		}
+		m, err := readMessage(ctx, r)
+		if err != nil {
+			if ctx.Err() != nil || !isRetryableReadError(err) {
+				return r
+			}
+			logger.WarnContext(ctx, "Recreating reader after transient error", logger.String("topic", topic), logger.ErrField(err))
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				return r
+			}
+			backoff *= 2
+			if backoff > readerRetryBackoffMax {
+				backoff = readerRetryBackoffMax
+			}
+			if r, err = reopen(r, next); err != nil {
+				logger.ErrorContext(ctx, "Failed to recreate reader", logger.String("topic", topic), logger.ErrField(err))
+				return nil
+			}
+			continue
+		}
+		backoff = readerRetryBackoff
+		next = m.Offset + 1
+		if k.cfg.OtelEnabled {
+			headers := make(map[string]string, len(m.Headers))
+			for _, h := range m.Headers {
+				headers[h.Key] = string(h.Value)
 			}
-			if k.cfg.OtelEnabled {
-				carrier := propagation.MapCarrier{}
-				for _, h := range m.Headers {
-					carrier[h.Key] = string(h.Value)
+			msgCtx := otel.ExtractContext(ctx, headers)
+			_, span := otel.StartSpanWithOptions(msgCtx, k.tracerName, "ConsumeMessage", messagingSpanOptions(oteltrace.SpanKindConsumer, topic)...)
+			span.End()
+		}
+		if !deliver(m) {
+			return r
+		}
+	}
+}
+
+// ConsumeDefault returns a channel to receive messages from the topic
+// configured by kafka_topic.
+func (k *Kafka) ConsumeDefault(ctx context.Context) (<-chan []byte, error) {
+	return k.Consume(ctx, k.cfg.Topic)
+}
+
+// TopicMessage is a message received by ConsumeMulti together with the topic
+// it was read from.
+type TopicMessage struct {
+	Topic string
+	Value []byte
+}
+
+// ConsumeMulti consumes several topics into a single channel. Each topic is
+// read by its own reader, shared with Consume, and every message is tagged
+// with its source topic. Messages from one topic keep their order; there is
+// no ordering across topics. The channel is closed once ctx is canceled or
+// every topic's reader has stopped.
+func (k *Kafka) ConsumeMulti(ctx context.Context, topics []string) (<-chan TopicMessage, error) {
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("no topics to consume")
+	}
+	seen := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		if seen[topic] {
+			return nil, fmt.Errorf("duplicate topic %q", topic)
+		}
+		seen[topic] = true
+	}
+
+	var span oteltrace.Span
+	if k.cfg.OtelEnabled {
+		ctx, span = otel.StartSpan(ctx, k.tracerName, "ConsumeMulti")
+		defer span.End()
+	}
+
+	out := make(chan TopicMessage)
+	var topicsWG sync.WaitGroup
+	for _, topic := range topics {
+		r := k.reader(topic)
+		values := make(chan []byte)
+		topicsWG.Add(1)
+		k.wg.Add(2)
+		go func() {
+			defer k.wg.Done()
+			defer close(values)
+			k.consumeLoop(ctx, topic, r, sendValue(ctx, values), func(old reader, next int64) (reader, error) {
+				return k.recreateReader(topic, old, next)
+			})
+		}()
+		go func() {
+			defer k.wg.Done()
+			defer topicsWG.Done()
+			for v := range values {
+				select {
+				case out <- TopicMessage{Topic: topic, Value: v}:
+				case <-ctx.Done():
+					return
 				}
-				msgCtx := otelglobal.GetTextMapPropagator().Extract(ctx, carrier)
-				_, span := otel.StartSpan(msgCtx, k.tracerName, "ConsumeMessage")
-				span.End()
 			}
-			out <- m.Value
+		}()
+	}
+	k.wg.Add(1)
+	go func() {
+		defer k.wg.Done()
+		topicsWG.Wait()
+		close(out)
+	}()
+	logger.InfoContext(ctx, "Consumer registered", logger.Any("topics", topics))
+	return out, nil
+}
+
+// MessageHandler processes the body of one message. ctx carries the trace
+// context propagated in the message headers when tracing is enabled.
+type MessageHandler func(ctx context.Context, body []byte) error
+
+// RetryOptions controls how ConsumeWithRetry handles messages whose handler
+// fails.
+type RetryOptions struct {
+	// MaxRetries is how many times a failed message is retried through the
+	// retry topic before it is sent to the dead-letter topic.
+	MaxRetries int
+	// Delay is how long a message waits on the retry topic, measured from
+	// the time it was republished, before it is handled again.
+	Delay time.Duration
+}
+
+// ConsumeWithRetry consumes topic and its retry topic (topic + ".retry") and
+// calls handler for every message until ctx is done. When handler fails, the
+// message is republished to the retry topic with RetryAttemptHeader
+// incremented and handled again once opts.Delay has passed. After
+// opts.MaxRetries retries a failing message is published to the dead-letter
+// topic (topic + ".dlq") instead. Both topics share the readers of Consume.
+func (k *Kafka) ConsumeWithRetry(ctx context.Context, topic string, handler MessageHandler, opts RetryOptions) error {
+	if opts.MaxRetries < 0 {
+		return fmt.Errorf("invalid max retries: %d", opts.MaxRetries)
+	}
+	if opts.Delay < 0 {
+		return fmt.Errorf("invalid retry delay: %s", opts.Delay)
+	}
+
+	retryTopic := topic + RetryTopicSuffix
+	for _, t := range []string{topic, retryTopic} {
+		r := k.reader(t)
+		delay := time.Duration(0)
+		if t == retryTopic {
+			delay = opts.Delay
+		}
+		k.wg.Add(1)
+		go func() {
+			defer k.wg.Done()
+			k.consumeLoop(ctx, t, r, func(m kafka_go.Message) bool {
+				if delay > 0 && !m.Time.IsZero() {
+					select {
+					case <-time.After(time.Until(m.Time.Add(delay))):
+					case <-ctx.Done():
+						return false
+					}
+				}
+				k.handleRetry(ctx, topic, m, handler, opts)
+				return ctx.Err() == nil
+			}, func(old reader, next int64) (reader, error) {
+				return k.recreateReader(t, old, next)
+			})
+		}()
+	}
+	logger.InfoContext(ctx, "Consumer registered", logger.String("topic", topic), logger.String("retry_topic", retryTopic), logger.Int("max_retries", opts.MaxRetries))
+	return nil
+}
+
+// handleRetry runs handler for m and, if it fails, republishes m to the retry
+// or dead-letter topic of topic.
+func (k *Kafka) handleRetry(ctx context.Context, topic string, m kafka_go.Message, handler MessageHandler, opts RetryOptions) {
+	msgCtx := ctx
+	if k.cfg.OtelEnabled {
+		headers := make(map[string]string, len(m.Headers))
+		for _, h := range m.Headers {
+			headers[h.Key] = string(h.Value)
+		}
+		msgCtx = otel.ExtractContext(ctx, headers)
+	}
+	handlerErr := handler(msgCtx, m.Value)
+	if handlerErr == nil {
+		return
+	}
+
+	attempt := retryAttempt(m.Headers)
+	next, count := topic+RetryTopicSuffix, attempt+1
+	if attempt >= opts.MaxRetries {
+		next, count = topic+DeadLetterTopicSuffix, attempt
+	}
+	headers := setHeader(m.Headers, RetryAttemptHeader, strconv.Itoa(count))
+	headers = setHeader(headers, RetryErrorHeader, handlerErr.Error())
+	fields := []interface{}{logger.String("topic", topic), logger.String("next_topic", next), logger.Int("attempt", attempt), logger.ErrField(handlerErr)}
+
+	w := k.writer(next)
+	if err := k.writeMessages(msgCtx, w, kafka_go.Message{Key: m.Key, Value: m.Value, Headers: headers}); err != nil {
+		k.discardBrokenWriter(msgCtx, next, w, err)
+		logger.ErrorContext(msgCtx, "Failed to republish failed message, dropping it", append(fields, logger.String("write_error", err.Error()))...)
+		return
+	}
+	logger.WarnContext(msgCtx, "Message handler failed, message republished", fields...)
+}
+
+// retryAttempt returns the RetryAttemptHeader of a message, or 0.
+func retryAttempt(headers []kafka_go.Header) int {
+	for _, h := range headers {
+		if h.Key == RetryAttemptHeader {
+			n, _ := strconv.Atoi(string(h.Value))
+			return n
+		}
+	}
+	return 0
+}
+
+// setHeader returns a copy of headers with key set to value.
+func setHeader(headers []kafka_go.Header, key, value string) []kafka_go.Header {
+	out := make([]kafka_go.Header, 0, len(headers)+1)
+	for _, h := range headers {
+		if h.Key != key {
+			out = append(out, h)
+		}
+	}
+	return append(out, kafka_go.Header{Key: key, Value: []byte(value)})
+}
+
+// ConsumeFrom returns a channel to receive messages from the specified topic
+// starting at the first message produced at or after since. Unlike Consume it
+// uses a dedicated reader positioned with an offset-for-time lookup, so it
+// can be used to reprocess messages without affecting other consumers.
+func (k *Kafka) ConsumeFrom(ctx context.Context, topic string, since time.Time) (<-chan []byte, error) {
+	var span oteltrace.Span
+	if k.cfg.OtelEnabled {
+		ctx, span = otel.StartSpan(ctx, k.tracerName, "ConsumeFrom")
+		defer span.End()
+	}
+
+	r, err := k.openSeeker(ctx, topic, since, -1)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(chan []byte)
+	k.wg.Add(1)
+	go func() {
+		defer k.wg.Done()
+		defer close(out)
+		r = k.consumeLoop(ctx, topic, r, sendValue(ctx, out), func(old reader, next int64) (reader, error) {
+			k.closeSeeker(old)
+			return k.openSeeker(ctx, topic, since, next)
+		})
+		if r != nil {
+			k.closeSeeker(r)
 		}
 	}()
-	logger.InfoContext(ctx, "Consumer registered", logger.String("topic", topic))
+	logger.InfoContext(ctx, "Consumer registered", logger.String("topic", topic), logger.String("since", since.Format(time.RFC3339Nano)))
 	return out, nil
 }
 
+// openSeeker creates a reader for topic positioned at offset next, or at the
+// first message at or after since when next is negative.
+func (k *Kafka) openSeeker(ctx context.Context, topic string, since time.Time, next int64) (reader, error) {
+	r := readerFactoryFunc(k.brokers, topic, k.cfg)
+	s, ok := r.(seeker)
+	if !ok {
+		_ = r.Close()
+		return nil, fmt.Errorf("reader for topic %s does not support seeking", topic)
+	}
+	if next >= 0 {
+		if err := s.SetOffset(next); err != nil {
+			_ = r.Close()
+			return nil, fmt.Errorf("failed to seek topic %s to offset %d: %w", topic, next, err)
+		}
+	} else if err := s.SetOffsetAt(ctx, since); err != nil {
+		_ = r.Close()
+		return nil, fmt.Errorf("failed to seek topic %s to %s: %w", topic, since.Format(time.RFC3339Nano), err)
+	}
+	k.mu.Lock()
+	k.seekers[r] = struct{}{}
+	k.mu.Unlock()
+	return r, nil
+}
+
+// closeSeeker closes a reader created by openSeeker.
+func (k *Kafka) closeSeeker(r reader) {
+	k.mu.Lock()
+	delete(k.seekers, r)
+	k.mu.Unlock()
+	_ = r.Close()
+}
+
+// Pause stops Consume goroutines for topic from reading further messages
+// until Resume is called. The reader stays open and nothing is buffered; a
+// message already read when Pause is called is still delivered.
+func (k *Kafka) Pause(topic string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.paused[topic]; !ok {
+		k.paused[topic] = make(chan struct{})
+		logger.Info("Consumption paused", logger.String("topic", topic))
+	}
+}
+
+// Resume restarts consumption of a topic paused with Pause.
+func (k *Kafka) Resume(topic string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if ch, ok := k.paused[topic]; ok {
+		close(ch)
+		delete(k.paused, topic)
+		logger.Info("Consumption resumed", logger.String("topic", topic))
+	}
+}
+
+// waitResumed blocks while topic is paused. It returns false if ctx is done
+// first.
+func (k *Kafka) waitResumed(ctx context.Context, topic string) bool {
+	k.mu.RLock()
+	ch, ok := k.paused[topic]
+	k.mu.RUnlock()
+	if !ok {
+		return true
+	}
+	select {
+	case <-ch:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// recreateReader closes old and replaces it with a new reader for topic. A
+// reader outside a consumer group would start over at the first offset, so it
+// is positioned at next instead when next is not negative.
+func (k *Kafka) recreateReader(topic string, old reader, next int64) (reader, error) {
+	_ = old.Close()
+	r := readerFactoryFunc(k.brokers, topic, k.cfg)
+	if s, ok := r.(seeker); ok && next >= 0 && !inGroup(r) {
+		if err := s.SetOffset(next); err != nil {
+			_ = r.Close()
+			return nil, fmt.Errorf("failed to seek topic %s to offset %d: %w", topic, next, err)
+		}
+	}
+	k.mu.Lock()
+	k.readers[topic] = r
+	k.mu.Unlock()
+	return r, nil
+}
+
+// inGroup reports whether r belongs to a consumer group, which tracks its own
+// committed offsets.
+func inGroup(r reader) bool {
+	kr, ok := r.(*kafka_go.Reader)
+	return ok && kr.Config().GroupID != ""
+}
+
+// isRetryableReadError reports whether a reader error is transient, such as a
+// consumer group rebalance, so that the reader should be recreated.
+func isRetryableReadError(err error) bool {
+	var kerr kafka_go.Error
+	if errors.As(err, &kerr) {
+		switch kerr {
+		case kafka_go.RebalanceInProgress, kafka_go.IllegalGeneration, kafka_go.UnknownMemberId:
+			return true
+		}
+		return kerr.Temporary()
+	}
+	var nerr net.Error
+	if errors.As(err, &nerr) && nerr.Timeout() {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// readMessage reads the next message from r, returning as soon as ctx is done
+// even if r does not honor cancellation itself.
+func readMessage(ctx context.Context, r reader) (kafka_go.Message, error) {
+	type result struct {
+		m   kafka_go.Message
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		m, err := r.ReadMessage(ctx)
+		done <- result{m, err}
+	}()
+	select {
+	case res := <-done:
+		return res.m, res.err
+	case <-ctx.Done():
+		return kafka_go.Message{}, ctx.Err()
+	}
+}
+
+// Wait blocks until every consumer goroutine started by Consume has exited,
+// which happens once its context is canceled or its reader fails.
+func (k *Kafka) Wait() {
+	k.wg.Wait()
+}
+
 // Close shuts down all readers and writers.
 func (k *Kafka) Close() error {
 	k.mu.Lock()
@@ -203,23 +987,86 @@ func (k *Kafka) Close() error {
 	for _, r := range k.readers {
 		_ = r.Close()
 	}
+	for r := range k.seekers {
+		_ = r.Close()
+	}
+	k.txMu.Lock()
+	if k.tx != nil {
+		_ = k.tx.Close()
+		k.tx, k.inTx = nil, false
+	}
+	k.txMu.Unlock()
 	k.writers = map[string]writer{}
 	k.readers = map[string]reader{}
+	k.seekers = map[reader]struct{}{}
 	logger.Info("Kafka closed")
 	return nil
 }
 
-// PublishJSON marshals v as JSON and publishes it to the specified topic.
-func PublishJSON[T any](ctx context.Context, k *Kafka, topic string, v T) error {
-	b, err := json.Marshal(v)
+// WriterStats returns the statistics of the cached writer for topic, such as
+// batch times, errors and bytes written. It reports false when no writer has
+// been created for topic yet or the writer does not provide statistics.
+// kafka-go resets the counters on every call, so each result covers the
+// period since the previous one.
+func (k *Kafka) WriterStats(topic string) (kafka_go.WriterStats, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	w, ok := k.writers[topic].(writerStatser)
+	if !ok {
+		return kafka_go.WriterStats{}, false
+	}
+	return w.Stats(), true
+}
+
+// ReaderStats returns the statistics of the reader used by Consume for topic,
+// such as lag, errors and bytes read. It reports false when no reader has been
+// created for topic yet or the reader does not provide statistics. As with
+// WriterStats, counters cover the period since the previous call.
+func (k *Kafka) ReaderStats(topic string) (kafka_go.ReaderStats, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	r, ok := k.readers[topic].(readerStatser)
+	if !ok {
+		return kafka_go.ReaderStats{}, false
+	}
+	return r.Stats(), true
+}
+
+// Codec encodes and decodes message bodies for Publish and Consume.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// JSONCodec encodes message bodies as JSON. It is the default codec.
+type JSONCodec struct{}
+
+// Marshal encodes v as JSON.
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) { return json.Marshal(v) }
+
+// Unmarshal decodes JSON data into v.
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+// Publish encodes v with codec and publishes it to the specified topic. A nil
+// codec uses JSONCodec.
+func Publish[T any](ctx context.Context, k *Kafka, topic string, codec Codec, v T) error {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+	b, err := codec.Marshal(v)
 	if err != nil {
 		return fmt.Errorf("marshal message: %w", err)
 	}
 	return k.Publish(ctx, topic, b)
 }
 
-// ConsumeJSON consumes messages from the topic and unmarshals them into type T.
-func ConsumeJSON[T any](ctx context.Context, k *Kafka, topic string) (<-chan T, error) {
+// Consume consumes messages from the topic and decodes them into type T with
+// codec. A nil codec uses JSONCodec. Messages that fail to decode are logged
+// and skipped.
+func Consume[T any](ctx context.Context, k *Kafka, topic string, codec Codec) (<-chan T, error) {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
 	byteCh, err := k.Consume(ctx, topic)
 	if err != nil {
 		return nil, err
@@ -229,12 +1076,26 @@ func ConsumeJSON[T any](ctx context.Context, k *Kafka, topic string) (<-chan T,
 		defer close(out)
 		for b := range byteCh {
 			var v T
-			if err := json.Unmarshal(b, &v); err != nil {
+			if err := codec.Unmarshal(b, &v); err != nil {
 				_ = logger.ErrorContext(ctx, "Failed to unmarshal message", logger.ErrField(err))
 				continue
 			}
-			out <- v
+			select {
+			case out <- v:
+			case <-ctx.Done():
+				return
+			}
 		}
 	}()
 	return out, nil
 }
+
+// PublishJSON marshals v as JSON and publishes it to the specified topic.
+func PublishJSON[T any](ctx context.Context, k *Kafka, topic string, v T) error {
+	return Publish(ctx, k, topic, JSONCodec{}, v)
+}
+
+// ConsumeJSON consumes messages from the topic and unmarshals them into type T.
+func ConsumeJSON[T any](ctx context.Context, k *Kafka, topic string) (<-chan T, error) {
+	return Consume[T](ctx, k, topic, JSONCodec{})
+}
@@ -3,8 +3,10 @@ package kafka
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"net"
 	"os"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -145,3 +147,63 @@ func TestPublishTracing(t *testing.T) {
 	require.Contains(t, logs, "\"trace_id\"")
 	require.Contains(t, logs, "\"span_id\"")
 }
+
+func TestConsumeFromSkipsEarlierMessages(t *testing.T) {
+	k := newKafkaForTest(t)
+	defer k.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	topic := fmt.Sprintf("consume-from-%d", time.Now().UnixNano())
+	require.NoError(t, k.Publish(ctx, topic, []byte("before")))
+	time.Sleep(100 * time.Millisecond)
+	since := time.Now()
+	time.Sleep(100 * time.Millisecond)
+	require.NoError(t, k.Publish(ctx, topic, []byte("after")))
+
+	msgs, err := k.ConsumeFrom(ctx, topic, since)
+	require.NoError(t, err)
+	select {
+	case msg := <-msgs:
+		require.Equal(t, []byte("after"), msg)
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for message")
+	}
+}
+
+func TestTransactionVisibleAfterCommit(t *testing.T) {
+	k := newKafkaForTest(t)
+	k.Close()
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"kafka_brokers":          strings.Join(k.brokers, ","),
+		"kafka_transactional_id": fmt.Sprintf("tx-test-%d", time.Now().UnixNano()),
+	}))
+	require.NoError(t, err)
+	k, err = New(cfg)
+	require.NoError(t, err)
+	defer k.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+	topic := fmt.Sprintf("tx-%d", time.Now().UnixNano())
+	// Create the topic before the transaction adds it.
+	require.NoError(t, k.Publish(ctx, topic, []byte("plain")))
+	msgs, err := k.Consume(ctx, topic)
+	require.NoError(t, err)
+	require.Equal(t, []byte("plain"), <-msgs)
+
+	require.NoError(t, k.BeginTx(ctx))
+	require.NoError(t, k.PublishTx(ctx, topic, []byte("in-tx")))
+	select {
+	case msg := <-msgs:
+		t.Fatalf("received %q before commit", msg)
+	case <-time.After(time.Second):
+	}
+	require.NoError(t, k.CommitTx(ctx))
+	select {
+	case msg := <-msgs:
+		require.Equal(t, []byte("in-tx"), msg)
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for committed message")
+	}
+}
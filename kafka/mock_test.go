@@ -1,18 +1,28 @@
 package kafka
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/gob"
 	"encoding/json"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	kafka_go "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
 
 	"github.com/T-Prohmpossadhorn/go-core/config"
 	"github.com/T-Prohmpossadhorn/go-core/otel"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 type mockWriter struct{ msgs []kafka_go.Message }
@@ -279,3 +289,685 @@ func TestKafkaCloseCallsClose(t *testing.T) {
 	require.Equal(t, 1, cw.closed)
 	require.Equal(t, 1, cr.closed)
 }
+
+// replyWriter answers every request with a correlated reply appended to the
+// partition log of r.
+type replyWriter struct{ r *partitionReader }
+
+func (w *replyWriter) WriteMessages(ctx context.Context, msgs ...kafka_go.Message) error {
+	for _, m := range msgs {
+		var id []byte
+		for _, h := range m.Headers {
+			if h.Key == CorrelationIDHeader {
+				id = h.Value
+			}
+		}
+		w.r.log = append(w.r.log,
+			kafka_go.Message{Value: []byte("wrong"), Headers: []kafka_go.Header{{Key: CorrelationIDHeader, Value: []byte("other")}}},
+			kafka_go.Message{Value: []byte("pong"), Headers: []kafka_go.Header{{Key: CorrelationIDHeader, Value: id}}},
+		)
+	}
+	return nil
+}
+
+func (w *replyWriter) Close() error { return nil }
+
+func TestKafkaRequestMock(t *testing.T) {
+	// An old reply for an earlier request sits before the end offset.
+	pr := &partitionReader{log: []kafka_go.Message{
+		{Value: []byte("stale"), Headers: []kafka_go.Header{{Key: CorrelationIDHeader, Value: []byte("earlier")}}},
+	}}
+	var replyTopic string
+	var partition int
+	origW, origP, origE := writerFactoryFunc, partitionReaderFactoryFunc, endOffsetsFunc
+	writerFactoryFunc = func([]string, string, Config) writer { return &replyWriter{r: pr} }
+	partitionReaderFactoryFunc = func(_ []string, topic string, p int, _ Config) reader {
+		replyTopic, partition = topic, p
+		return pr
+	}
+	endOffsetsFunc = func(context.Context, []string, string, Config) (map[int]int64, error) {
+		return map[int]int64{2: 1}, nil
+	}
+	defer func() { writerFactoryFunc, partitionReaderFactoryFunc, endOffsetsFunc = origW, origP, origE }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	k, err := New(cfg)
+	require.NoError(t, err)
+
+	reply, err := k.Request(context.Background(), "requests", "replies", []byte("ping"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("pong"), reply)
+	require.Equal(t, "replies", replyTopic)
+	require.Equal(t, 2, partition)
+	require.Equal(t, 2, pr.reads, "only messages after the end offset are read")
+}
+
+// seekErrReader is an errReader that can be positioned.
+type seekErrReader struct{ errReader }
+
+func (seekErrReader) SetOffset(int64) error                        { return nil }
+func (seekErrReader) SetOffsetAt(context.Context, time.Time) error { return nil }
+
+func TestKafkaRequestReaderErrorMock(t *testing.T) {
+	origW, origP, origE := writerFactoryFunc, partitionReaderFactoryFunc, endOffsetsFunc
+	writerFactoryFunc = func([]string, string, Config) writer { return &mockWriter{} }
+	partitionReaderFactoryFunc = func([]string, string, int, Config) reader { return &seekErrReader{} }
+	endOffsetsFunc = func(context.Context, []string, string, Config) (map[int]int64, error) {
+		return map[int]int64{0: 0}, nil
+	}
+	defer func() { writerFactoryFunc, partitionReaderFactoryFunc, endOffsetsFunc = origW, origP, origE }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	k, err := New(cfg)
+	require.NoError(t, err)
+
+	_, err = k.Request(context.Background(), "requests", "replies", []byte("ping"))
+	require.ErrorContains(t, err, "boom")
+
+	endOffsetsFunc = func(context.Context, []string, string, Config) (map[int]int64, error) {
+		return nil, fmt.Errorf("unknown topic")
+	}
+	_, err = k.Request(context.Background(), "requests", "replies", []byte("ping"))
+	require.ErrorContains(t, err, "unknown topic")
+}
+
+func TestKafkaConsumeCancelClosesChannel(t *testing.T) {
+	// mockReader blocks on its channel and ignores ctx.
+	mr := &mockReader{ch: make(chan kafka_go.Message)}
+	origR := readerFactoryFunc
+	readerFactoryFunc = func([]string, string, Config) reader { return mr }
+	defer func() { readerFactoryFunc = origR }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	k, err := New(cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out, err := k.Consume(ctx, "t1")
+	require.NoError(t, err)
+	cancel()
+
+	select {
+	case _, ok := <-out:
+		require.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+
+	waited := make(chan struct{})
+	go func() { k.Wait(); close(waited) }()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return after cancel")
+	}
+	close(mr.ch)
+}
+
+func TestKafkaSpanKindsMock(t *testing.T) {
+	mw := &mockWriter{}
+	mr := &mockReader{ch: make(chan kafka_go.Message, 1)}
+	origW, origR := writerFactoryFunc, readerFactoryFunc
+	writerFactoryFunc = func([]string, string, Config) writer { return mw }
+	readerFactoryFunc = func([]string, string, Config) reader { return mr }
+	defer func() { writerFactoryFunc, readerFactoryFunc = origW, origR }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{
+		"otel_enabled": true,
+	}))
+	os.Setenv("OTEL_TEST_MOCK_EXPORTER", "true")
+	defer os.Unsetenv("OTEL_TEST_MOCK_EXPORTER")
+	require.NoError(t, otel.Init(cfg))
+	defer otel.Shutdown(context.Background())
+	recorder := tracetest.NewSpanRecorder()
+	otel.GetTracerProvider().RegisterSpanProcessor(recorder)
+
+	k, err := New(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, k.Publish(context.Background(), "t1", []byte("x")))
+	mr.ch <- mw.msgs[0]
+	close(mr.ch)
+	out, err := k.Consume(context.Background(), "t1")
+	require.NoError(t, err)
+	<-out
+
+	kinds := map[string]oteltrace.SpanKind{}
+	for _, s := range recorder.Ended() {
+		kinds[s.Name()] = s.SpanKind()
+	}
+	require.Equal(t, oteltrace.SpanKindProducer, kinds["Publish"])
+	require.Equal(t, oteltrace.SpanKindConsumer, kinds["ConsumeMessage"])
+}
+
+func TestKafkaPauseResumeMock(t *testing.T) {
+	mr := &mockReader{ch: make(chan kafka_go.Message, 1)}
+	origR := readerFactoryFunc
+	readerFactoryFunc = func([]string, string, Config) reader { return mr }
+	defer func() { readerFactoryFunc = origR }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	k, err := New(cfg)
+	require.NoError(t, err)
+
+	k.Pause("t1")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out, err := k.Consume(ctx, "t1")
+	require.NoError(t, err)
+
+	mr.ch <- kafka_go.Message{Value: []byte("held")}
+	select {
+	case msg := <-out:
+		t.Fatalf("received %q while paused", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+	// The message must stay with the reader rather than being buffered
+	require.Len(t, mr.ch, 1)
+
+	k.Resume("t1")
+	select {
+	case msg := <-out:
+		require.Equal(t, "held", string(msg))
+	case <-time.After(time.Second):
+		t.Fatal("consumption did not resume")
+	}
+}
+
+func TestKafkaDefaultTopicMock(t *testing.T) {
+	var topics []string
+	mw := &mockWriter{}
+	mr := &mockReader{ch: make(chan kafka_go.Message, 1)}
+	mr.ch <- kafka_go.Message{Value: []byte("consumed")}
+	origW, origR := writerFactoryFunc, readerFactoryFunc
+	writerFactoryFunc = func(_ []string, topic string, _ Config) writer {
+		topics = append(topics, topic)
+		return mw
+	}
+	readerFactoryFunc = func(_ []string, topic string, _ Config) reader {
+		topics = append(topics, topic)
+		return mr
+	}
+	defer func() { writerFactoryFunc, readerFactoryFunc = origW, origR }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{
+		"kafka_topic": "orders",
+	}))
+	k, err := New(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, k.PublishDefault(context.Background(), []byte("hello")))
+	require.Len(t, mw.msgs, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	out, err := k.ConsumeDefault(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []byte("consumed"), <-out)
+	require.Equal(t, []string{"orders", "orders"}, topics)
+}
+
+// gobCodec is a non-JSON codec for testing.
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func TestKafkaPublishConsumeCodecMock(t *testing.T) {
+	type msg struct {
+		Name  string
+		Count int
+	}
+
+	mw := &mockWriter{}
+	mr := &mockReader{ch: make(chan kafka_go.Message, 1)}
+
+	origW, origR := writerFactoryFunc, readerFactoryFunc
+	writerFactoryFunc = func([]string, string, Config) writer { return mw }
+	readerFactoryFunc = func([]string, string, Config) reader { return mr }
+	defer func() { writerFactoryFunc, readerFactoryFunc = origW, origR }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	k, err := New(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, Publish(context.Background(), k, "t1", gobCodec{}, msg{Name: "hello", Count: 3}))
+	require.Len(t, mw.msgs, 1)
+	require.False(t, json.Valid(mw.msgs[0].Value), "body should be gob encoded")
+
+	// Feed the published bytes back through the reader
+	mr.ch <- mw.msgs[0]
+	close(mr.ch)
+	out, err := Consume[msg](context.Background(), k, "t1", gobCodec{})
+	require.NoError(t, err)
+	require.Equal(t, msg{Name: "hello", Count: 3}, <-out)
+}
+
+func TestKafkaPublishErrorSpanStatusMock(t *testing.T) {
+	origW := writerFactoryFunc
+	writerFactoryFunc = func([]string, string, Config) writer { return &errWriter{} }
+	defer func() { writerFactoryFunc = origW }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{
+		"otel_enabled": true,
+	}))
+	os.Setenv("OTEL_TEST_MOCK_EXPORTER", "true")
+	defer os.Unsetenv("OTEL_TEST_MOCK_EXPORTER")
+	require.NoError(t, otel.Init(cfg))
+	defer otel.Shutdown(context.Background())
+	recorder := tracetest.NewSpanRecorder()
+	otel.GetTracerProvider().RegisterSpanProcessor(recorder)
+
+	k, err := New(cfg)
+	require.NoError(t, err)
+	require.Error(t, k.Publish(context.Background(), "t1", []byte("x")))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "Publish", spans[0].Name())
+	require.Equal(t, codes.Error, spans[0].Status().Code)
+	require.Contains(t, spans[0].Status().Description, "write fail")
+	require.Len(t, spans[0].Events(), 1)
+	require.Equal(t, "exception", spans[0].Events()[0].Name)
+}
+
+func TestKafkaConsumeFromRequiresSeekableReaderMock(t *testing.T) {
+	mr := &mockReader{ch: make(chan kafka_go.Message)}
+	origR := readerFactoryFunc
+	readerFactoryFunc = func([]string, string, Config) reader { return mr }
+	defer func() { readerFactoryFunc = origR }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	k, err := New(cfg)
+	require.NoError(t, err)
+	defer k.Close()
+
+	_, err = k.ConsumeFrom(context.Background(), "t1", time.Now())
+	require.ErrorContains(t, err, "does not support seeking")
+}
+
+// statsWriter and statsReader are fakes that report statistics.
+type statsWriter struct{ mockWriter }
+
+func (s *statsWriter) Stats() kafka_go.WriterStats {
+	return kafka_go.WriterStats{Writes: int64(len(s.msgs)), Topic: "t1"}
+}
+
+type statsReader struct{ mockReader }
+
+func (s *statsReader) Stats() kafka_go.ReaderStats {
+	return kafka_go.ReaderStats{Messages: 7, Lag: 3, Topic: "t1"}
+}
+
+func TestKafkaStatsMock(t *testing.T) {
+	sw := &statsWriter{}
+	sr := &statsReader{mockReader{ch: make(chan kafka_go.Message)}}
+	origW, origR := writerFactoryFunc, readerFactoryFunc
+	writerFactoryFunc = func([]string, string, Config) writer { return sw }
+	readerFactoryFunc = func([]string, string, Config) reader { return sr }
+	defer func() { writerFactoryFunc, readerFactoryFunc = origW, origR }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	k, err := New(cfg)
+	require.NoError(t, err)
+
+	_, ok := k.WriterStats("t1")
+	require.False(t, ok, "no writer before the first publish")
+	_, ok = k.ReaderStats("t1")
+	require.False(t, ok, "no reader before the first consume")
+
+	require.NoError(t, k.Publish(context.Background(), "t1", []byte("a")))
+	require.NoError(t, k.Publish(context.Background(), "t1", []byte("b")))
+	ws, ok := k.WriterStats("t1")
+	require.True(t, ok)
+	require.Equal(t, int64(2), ws.Writes)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = k.Consume(ctx, "t1")
+	require.NoError(t, err)
+	rs, ok := k.ReaderStats("t1")
+	require.True(t, ok)
+	require.Equal(t, int64(7), rs.Messages)
+	require.Equal(t, int64(3), rs.Lag)
+	cancel()
+	k.Wait()
+
+	// Implementations without Stats report false
+	mw := &mockWriter{}
+	writerFactoryFunc = func([]string, string, Config) writer { return mw }
+	require.NoError(t, k.Publish(context.Background(), "t2", []byte("c")))
+	_, ok = k.WriterStats("t2")
+	require.False(t, ok)
+}
+
+// blockingWriter blocks every write until its context is done.
+type blockingWriter struct{}
+
+func (blockingWriter) WriteMessages(ctx context.Context, msgs ...kafka_go.Message) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (blockingWriter) Close() error { return nil }
+
+func TestKafkaWriteTimeoutMock(t *testing.T) {
+	origW := writerFactoryFunc
+	writerFactoryFunc = func([]string, string, Config) writer { return blockingWriter{} }
+	defer func() { writerFactoryFunc = origW }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{"kafka_write_timeout_ms": 50}))
+	k, err := New(cfg)
+	require.NoError(t, err)
+
+	start := time.Now()
+	err = k.Publish(context.Background(), "t1", []byte("x"))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 2*time.Second)
+
+	origP, origE := partitionReaderFactoryFunc, endOffsetsFunc
+	partitionReaderFactoryFunc = func([]string, string, int, Config) reader { return &partitionReader{} }
+	endOffsetsFunc = func(context.Context, []string, string, Config) (map[int]int64, error) {
+		return map[int]int64{0: 0}, nil
+	}
+	defer func() { partitionReaderFactoryFunc, endOffsetsFunc = origP, origE }()
+	_, err = k.Request(context.Background(), "req", "reply", []byte("x"))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestKafkaInvalidWriteTimeout(t *testing.T) {
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{"kafka_write_timeout_ms": -1}))
+	_, err := New(cfg)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "invalid kafka_write_timeout_ms")
+}
+
+func TestKafkaConsumeMultiMock(t *testing.T) {
+	readers := map[string]*mockReader{
+		"orders":   {ch: make(chan kafka_go.Message, 2)},
+		"payments": {ch: make(chan kafka_go.Message, 2)},
+	}
+	origR := readerFactoryFunc
+	readerFactoryFunc = func(_ []string, topic string, _ Config) reader { return readers[topic] }
+	defer func() { readerFactoryFunc = origR }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	k, err := New(cfg)
+	require.NoError(t, err)
+
+	_, err = k.ConsumeMulti(context.Background(), nil)
+	require.Error(t, err)
+	_, err = k.ConsumeMulti(context.Background(), []string{"orders", "orders"})
+	require.ErrorContains(t, err, "duplicate topic")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	out, err := k.ConsumeMulti(ctx, []string{"orders", "payments"})
+	require.NoError(t, err)
+
+	readers["orders"].ch <- kafka_go.Message{Value: []byte("order-1")}
+	readers["payments"].ch <- kafka_go.Message{Value: []byte("payment-1")}
+	readers["orders"].ch <- kafka_go.Message{Value: []byte("order-2")}
+
+	got := map[string][]string{}
+	for i := 0; i < 3; i++ {
+		select {
+		case m := <-out:
+			got[m.Topic] = append(got[m.Topic], string(m.Value))
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for messages")
+		}
+	}
+	require.Equal(t, map[string][]string{
+		"orders":   {"order-1", "order-2"},
+		"payments": {"payment-1"},
+	}, got)
+
+	cancel()
+	select {
+	case _, ok := <-out:
+		require.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed after cancel")
+	}
+	waited := make(chan struct{})
+	go func() { k.Wait(); close(waited) }()
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("Wait did not return after cancel")
+	}
+	close(readers["orders"].ch)
+	close(readers["payments"].ch)
+}
+
+// chanWriter forwards written messages to a channel.
+type chanWriter struct{ ch chan kafka_go.Message }
+
+func (w *chanWriter) WriteMessages(ctx context.Context, msgs ...kafka_go.Message) error {
+	for _, m := range msgs {
+		m.Time = time.Now()
+		w.ch <- m
+	}
+	return nil
+}
+
+func (w *chanWriter) Close() error { return nil }
+
+func TestKafkaConsumeWithRetryMock(t *testing.T) {
+	primary := &mockReader{ch: make(chan kafka_go.Message, 2)}
+	retry := &mockReader{ch: make(chan kafka_go.Message, 2)}
+	dead := make(chan kafka_go.Message, 1)
+	origW, origR := writerFactoryFunc, readerFactoryFunc
+	readerFactoryFunc = func(_ []string, topic string, _ Config) reader {
+		if topic == "orders.retry" {
+			return retry
+		}
+		return primary
+	}
+	writerFactoryFunc = func(_ []string, topic string, _ Config) writer {
+		if topic == "orders.retry" {
+			// Feed retried messages back to the consumer, as the broker would.
+			return &chanWriter{ch: retry.ch}
+		}
+		return &chanWriter{ch: dead}
+	}
+	defer func() { writerFactoryFunc, readerFactoryFunc = origW, origR }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	k, err := New(cfg)
+	require.NoError(t, err)
+
+	require.Error(t, k.ConsumeWithRetry(context.Background(), "orders", nil, RetryOptions{MaxRetries: -1}))
+
+	var mu sync.Mutex
+	var handled []string
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	start := time.Now()
+	err = k.ConsumeWithRetry(ctx, "orders", func(_ context.Context, body []byte) error {
+		mu.Lock()
+		handled = append(handled, string(body))
+		mu.Unlock()
+		if string(body) == "poison" {
+			return fmt.Errorf("cannot process %s", body)
+		}
+		return nil
+	}, RetryOptions{MaxRetries: 2, Delay: 20 * time.Millisecond})
+	require.NoError(t, err)
+
+	primary.ch <- kafka_go.Message{Key: []byte("k1"), Value: []byte("poison")}
+	primary.ch <- kafka_go.Message{Value: []byte("ok")}
+
+	select {
+	case m := <-dead:
+		require.Equal(t, []byte("poison"), m.Value)
+		require.Equal(t, []byte("k1"), m.Key)
+		headers := map[string]string{}
+		for _, h := range m.Headers {
+			headers[h.Key] = string(h.Value)
+		}
+		require.Equal(t, "2", headers[RetryAttemptHeader])
+		require.Equal(t, "cannot process poison", headers[RetryErrorHeader])
+	case <-time.After(time.Second):
+		t.Fatal("message was not dead-lettered")
+	}
+	// Each of the two retries waited for the delay.
+	require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	mu.Lock()
+	require.ElementsMatch(t, []string{"poison", "ok", "poison", "poison"}, handled)
+	mu.Unlock()
+
+	cancel()
+	close(primary.ch)
+	close(retry.ch)
+	k.Wait()
+}
+
+func TestKafkaPublishMessageTooLarge(t *testing.T) {
+	mw := &mockWriter{}
+	origW := writerFactoryFunc
+	writerFactoryFunc = func([]string, string, Config) writer { return mw }
+	defer func() { writerFactoryFunc = origW }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{"kafka_max_message_bytes": 16}))
+	k, err := New(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, k.Publish(context.Background(), "t1", []byte("small")))
+	err = k.Publish(context.Background(), "t1", []byte("this payload is too large"))
+	require.ErrorIs(t, err, ErrMessageTooLarge)
+	require.Contains(t, err.Error(), "25 bytes exceeds kafka_max_message_bytes 16")
+	require.Len(t, mw.msgs, 1)
+
+	// The default limit rejects anything over 1 MiB
+	cfg, _ = config.New()
+	k, err = New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, k.Publish(context.Background(), "t1", make([]byte, DefaultMaxMessageBytes)))
+	require.ErrorIs(t, k.Publish(context.Background(), "t1", make([]byte, DefaultMaxMessageBytes+1)), ErrMessageTooLarge)
+
+	cfg, _ = config.New(config.WithDefault(map[string]interface{}{"kafka_max_message_bytes": -1}))
+	_, err = New(cfg)
+	require.Error(t, err)
+}
+
+// txBroker is a fake transactional producer that, like a broker serving
+// read_committed consumers, only exposes messages of committed transactions.
+type txBroker struct {
+	begins    int
+	pending   []kafka_go.Message
+	committed []kafka_go.Message
+	closed    bool
+}
+
+func (b *txBroker) Begin(context.Context) error { b.begins++; return nil }
+
+func (b *txBroker) Produce(_ context.Context, topic string, msgs ...kafka_go.Message) error {
+	for _, m := range msgs {
+		m.Topic = topic
+		b.pending = append(b.pending, m)
+	}
+	return nil
+}
+
+func (b *txBroker) Commit(context.Context) error {
+	b.committed = append(b.committed, b.pending...)
+	b.pending = nil
+	return nil
+}
+
+func (b *txBroker) Abort(context.Context) error { b.pending = nil; return nil }
+func (b *txBroker) Close() error                { b.closed = true; return nil }
+
+func TestKafkaTransactionMock(t *testing.T) {
+	broker := &txBroker{}
+	var transactionalID string
+	origT := txProducerFactoryFunc
+	txProducerFactoryFunc = func(_ []string, cfg Config) txProducer {
+		transactionalID = cfg.TransactionalID
+		return broker
+	}
+	defer func() { txProducerFactoryFunc = origT }()
+
+	ctx := context.Background()
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	k, err := New(cfg)
+	require.NoError(t, err)
+	require.ErrorContains(t, k.BeginTx(ctx), "kafka_transactional_id is not configured")
+
+	cfg, _ = config.New(config.WithDefault(map[string]interface{}{"kafka_transactional_id": "orders-tx"}))
+	k, err = New(cfg)
+	require.NoError(t, err)
+	require.ErrorIs(t, k.PublishTx(ctx, "orders", []byte("x")), ErrNoTransaction)
+	require.ErrorIs(t, k.CommitTx(ctx), ErrNoTransaction)
+
+	require.NoError(t, k.BeginTx(ctx))
+	require.Equal(t, "orders-tx", transactionalID)
+	require.ErrorContains(t, k.BeginTx(ctx), "already in progress")
+	require.NoError(t, k.PublishTx(ctx, "orders", []byte("a")))
+	require.NoError(t, k.PublishTx(ctx, "payments", []byte("b")))
+	require.Empty(t, broker.committed, "messages must not be visible before commit")
+	require.NoError(t, k.CommitTx(ctx))
+	require.Len(t, broker.committed, 2)
+	require.Equal(t, "orders", broker.committed[0].Topic)
+	require.Equal(t, []byte("b"), broker.committed[1].Value)
+
+	// Aborted messages never become visible
+	require.NoError(t, k.BeginTx(ctx))
+	require.NoError(t, k.PublishTx(ctx, "orders", []byte("c")))
+	require.NoError(t, k.AbortTx(ctx))
+	require.Len(t, broker.committed, 2)
+	require.Empty(t, broker.pending)
+	require.Equal(t, 2, broker.begins)
+
+	require.NoError(t, k.BeginTx(ctx))
+	require.ErrorIs(t, k.PublishTx(ctx, "orders", make([]byte, DefaultMaxMessageBytes+1)), ErrMessageTooLarge)
+	require.NoError(t, k.Close())
+	require.True(t, broker.closed)
+}
+
+func TestEncodeTxBatch(t *testing.T) {
+	raw, err := encodeTxBatch([]kafka_go.Message{
+		{Key: []byte("k1"), Value: []byte("v1")},
+		{Value: []byte("v2"), Headers: []kafka_go.Header{{Key: "h", Value: []byte("1")}}},
+	}, 42, 3, 7)
+	require.NoError(t, err)
+	b, err := io.ReadAll(raw.Reader)
+	require.NoError(t, err)
+
+	batch := b[4:]
+	crc := crc32.Checksum(batch[batchAttributesOffset:], crc32.MakeTable(crc32.Castagnoli))
+	require.Equal(t, crc, binary.BigEndian.Uint32(batch[batchCRCOffset:]))
+
+	var rs protocol.RecordSet
+	_, err = rs.ReadFrom(bytes.NewReader(b))
+	require.NoError(t, err)
+	require.True(t, rs.Attributes.Transactional())
+	stream, ok := rs.Records.(*protocol.RecordStream)
+	require.True(t, ok)
+	require.Len(t, stream.Records, 1)
+	rb, ok := stream.Records[0].(*protocol.RecordBatch)
+	require.True(t, ok)
+	require.Equal(t, int64(42), rb.ProducerID)
+	require.Equal(t, int16(3), rb.ProducerEpoch)
+	require.Equal(t, int32(7), rb.BaseSequence)
+
+	var values []string
+	for {
+		r, err := rs.Records.ReadRecord()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		v, err := protocol.ReadAll(r.Value)
+		require.NoError(t, err)
+		values = append(values, string(v))
+	}
+	require.Equal(t, []string{"v1", "v2"}, values)
+}
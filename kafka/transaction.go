@@ -0,0 +1,329 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"time"
+
+	kafka_go "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/protocol"
+
+	"github.com/T-Prohmpossadhorn/go-core/logger"
+	"github.com/T-Prohmpossadhorn/go-core/otel"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// ErrNoTransaction is returned, wrapped, by PublishTx, CommitTx and AbortTx
+// when no transaction has been started with BeginTx.
+var ErrNoTransaction = errors.New("no transaction in progress")
+
+// transactionTimeout is how long the broker keeps a transaction open before
+// aborting it.
+const transactionTimeout = time.Minute
+
+// txProducer produces messages as part of transactions of a single
+// transactional id.
+type txProducer interface {
+	Begin(ctx context.Context) error
+	Produce(ctx context.Context, topic string, msgs ...kafka_go.Message) error
+	Commit(ctx context.Context) error
+	Abort(ctx context.Context) error
+	Close() error
+}
+
+// txProducerFactoryFunc creates the transactional producer for
+// kafka_transactional_id.
+var txProducerFactoryFunc = func(brokers []string, cfg Config) txProducer {
+	t := newTransport(cfg)
+	return &txClient{
+		client:    &kafka_go.Client{Addr: kafka_go.TCP(brokers...), Transport: t},
+		transport: t,
+		id:        cfg.TransactionalID,
+		balancer:  &kafka_go.LeastBytes{},
+	}
+}
+
+// BeginTx starts a transaction for the producer identified by
+// kafka_transactional_id. Messages published with PublishTx are only visible
+// to consumers once CommitTx succeeds. Only one transaction can be open at a
+// time.
+func (k *Kafka) BeginTx(ctx context.Context) error {
+	if k.cfg.TransactionalID == "" {
+		return fmt.Errorf("begin transaction: kafka_transactional_id is not configured")
+	}
+	k.txMu.Lock()
+	defer k.txMu.Unlock()
+	if k.inTx {
+		return fmt.Errorf("begin transaction: transaction already in progress")
+	}
+	if k.tx == nil {
+		k.tx = txProducerFactoryFunc(k.brokers, k.cfg)
+	}
+	if err := k.tx.Begin(ctx); err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	k.inTx = true
+	logger.InfoContext(ctx, "Transaction started", logger.String("transactional_id", k.cfg.TransactionalID))
+	return nil
+}
+
+// PublishTx sends a message to the specified topic as part of the transaction
+// started with BeginTx.
+func (k *Kafka) PublishTx(ctx context.Context, topic string, body []byte) (err error) {
+	var span oteltrace.Span
+	if k.cfg.OtelEnabled {
+		ctx, span = otel.StartSpanWithOptions(ctx, k.tracerName, "PublishTx", messagingSpanOptions(oteltrace.SpanKindProducer, topic)...)
+		defer func() { endSpan(span, err) }()
+	}
+	if ctx.Err() != nil {
+		return fmt.Errorf("publish canceled: %w", ctx.Err())
+	}
+
+	m := kafka_go.Message{Value: body, Headers: k.traceHeaders(ctx)}
+	if err = k.checkMessageSize(m); err != nil {
+		return err
+	}
+	k.txMu.Lock()
+	defer k.txMu.Unlock()
+	if !k.inTx {
+		return fmt.Errorf("publish: %w", ErrNoTransaction)
+	}
+	if err = k.tx.Produce(ctx, topic, m); err != nil {
+		return fmt.Errorf("write message: %w", err)
+	}
+	logger.InfoContext(ctx, "Message published in transaction", logger.String("topic", topic))
+	return nil
+}
+
+// CommitTx commits the transaction started with BeginTx, making its messages
+// visible to consumers. If it fails the transaction stays open and should be
+// ended with AbortTx.
+func (k *Kafka) CommitTx(ctx context.Context) error {
+	k.txMu.Lock()
+	defer k.txMu.Unlock()
+	if !k.inTx {
+		return fmt.Errorf("commit transaction: %w", ErrNoTransaction)
+	}
+	if err := k.tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	k.inTx = false
+	logger.InfoContext(ctx, "Transaction committed", logger.String("transactional_id", k.cfg.TransactionalID))
+	return nil
+}
+
+// AbortTx aborts the transaction started with BeginTx, discarding its
+// messages. The transaction is ended even if the abort request fails; the
+// next BeginTx then re-registers the producer, which makes the broker abort
+// it.
+func (k *Kafka) AbortTx(ctx context.Context) error {
+	k.txMu.Lock()
+	defer k.txMu.Unlock()
+	if !k.inTx {
+		return fmt.Errorf("abort transaction: %w", ErrNoTransaction)
+	}
+	k.inTx = false
+	if err := k.tx.Abort(ctx); err != nil {
+		return fmt.Errorf("abort transaction: %w", err)
+	}
+	logger.InfoContext(ctx, "Transaction aborted", logger.String("transactional_id", k.cfg.TransactionalID))
+	return nil
+}
+
+// topicPartition identifies a partition of a topic.
+type topicPartition struct {
+	topic     string
+	partition int
+}
+
+// txClient implements txProducer with kafka-go's low-level client. kafka-go
+// has no transactional writer, so record batches are encoded with the
+// producer id, epoch and sequence numbers assigned by the transaction
+// coordinator and sent as raw produce requests.
+type txClient struct {
+	client    *kafka_go.Client
+	transport *kafka_go.Transport
+	id        string
+	balancer  kafka_go.Balancer
+
+	// producerID and epoch are valid while registered is true.
+	registered bool
+	producerID int
+	epoch      int
+	sequences  map[topicPartition]int32
+	added      map[topicPartition]bool
+}
+
+// Begin registers the producer with the transaction coordinator on first use,
+// which also fences off older producers with the same transactional id.
+func (c *txClient) Begin(ctx context.Context) error {
+	if !c.registered {
+		res, err := c.client.InitProducerID(ctx, &kafka_go.InitProducerIDRequest{
+			TransactionalID:      c.id,
+			TransactionTimeoutMs: int(transactionTimeout / time.Millisecond),
+			ProducerID:           -1,
+			ProducerEpoch:        -1,
+		})
+		if err == nil {
+			err = res.Error
+		}
+		if err != nil {
+			return fmt.Errorf("init producer id: %w", err)
+		}
+		c.producerID, c.epoch = res.Producer.ProducerID, res.Producer.ProducerEpoch
+		c.sequences = make(map[topicPartition]int32)
+		c.registered = true
+	}
+	c.added = make(map[topicPartition]bool)
+	return nil
+}
+
+// Produce writes msgs to partitions of topic chosen by the balancer, adding
+// each partition to the transaction before its first write.
+func (c *txClient) Produce(ctx context.Context, topic string, msgs ...kafka_go.Message) error {
+	meta, err := c.client.Metadata(ctx, &kafka_go.MetadataRequest{Topics: []string{topic}})
+	if err != nil {
+		return err
+	}
+	if len(meta.Topics) == 0 {
+		return fmt.Errorf("topic %s not found", topic)
+	}
+	if meta.Topics[0].Error != nil {
+		return meta.Topics[0].Error
+	}
+	partitions := make([]int, len(meta.Topics[0].Partitions))
+	for i, p := range meta.Topics[0].Partitions {
+		partitions[i] = p.ID
+	}
+	if len(partitions) == 0 {
+		return fmt.Errorf("topic %s has no partitions", topic)
+	}
+
+	batches := make(map[int][]kafka_go.Message)
+	for _, m := range msgs {
+		p := c.balancer.Balance(m, partitions...)
+		batches[p] = append(batches[p], m)
+	}
+	for p, batch := range batches {
+		tp := topicPartition{topic, p}
+		if !c.added[tp] {
+			if err := c.addPartition(ctx, tp); err != nil {
+				return err
+			}
+			c.added[tp] = true
+		}
+		records, err := encodeTxBatch(batch, c.producerID, c.epoch, c.sequences[tp])
+		if err != nil {
+			return err
+		}
+		res, err := c.client.RawProduce(ctx, &kafka_go.RawProduceRequest{
+			Topic:           topic,
+			Partition:       p,
+			RequiredAcks:    kafka_go.RequireAll,
+			TransactionalID: c.id,
+			RawRecords:      records,
+		})
+		if err == nil {
+			err = res.Error
+		}
+		if err != nil {
+			return fmt.Errorf("produce to topic %s partition %d: %w", topic, p, err)
+		}
+		c.sequences[tp] += int32(len(batch))
+	}
+	return nil
+}
+
+// addPartition adds tp to the open transaction.
+func (c *txClient) addPartition(ctx context.Context, tp topicPartition) error {
+	res, err := c.client.AddPartitionsToTxn(ctx, &kafka_go.AddPartitionsToTxnRequest{
+		TransactionalID: c.id,
+		ProducerID:      c.producerID,
+		ProducerEpoch:   c.epoch,
+		Topics:          map[string][]kafka_go.AddPartitionToTxn{tp.topic: {{Partition: tp.partition}}},
+	})
+	if err != nil {
+		return fmt.Errorf("add topic %s partition %d to transaction: %w", tp.topic, tp.partition, err)
+	}
+	for _, p := range res.Topics[tp.topic] {
+		if p.Error != nil {
+			return fmt.Errorf("add topic %s partition %d to transaction: %w", tp.topic, tp.partition, p.Error)
+		}
+	}
+	return nil
+}
+
+// Commit commits the open transaction.
+func (c *txClient) Commit(ctx context.Context) error { return c.end(ctx, true) }
+
+// Abort aborts the open transaction.
+func (c *txClient) Abort(ctx context.Context) error { return c.end(ctx, false) }
+
+// end commits or aborts the open transaction. A failed abort drops the
+// producer registration so the next Begin fences the transaction off.
+func (c *txClient) end(ctx context.Context, commit bool) error {
+	res, err := c.client.EndTxn(ctx, &kafka_go.EndTxnRequest{
+		TransactionalID: c.id,
+		ProducerID:      c.producerID,
+		ProducerEpoch:   c.epoch,
+		Committed:       commit,
+	})
+	if err == nil {
+		err = res.Error
+	}
+	if err != nil && !commit {
+		c.registered = false
+	}
+	return err
+}
+
+// Close releases the connections of the client.
+func (c *txClient) Close() error {
+	c.transport.CloseIdleConnections()
+	return nil
+}
+
+// Offsets of record batch fields rewritten by encodeTxBatch, relative to the
+// start of the batch. The CRC covers everything from the attributes to the
+// end of the batch.
+const (
+	batchCRCOffset        = 17
+	batchAttributesOffset = 21
+	batchProducerIDOffset = 43
+	batchEpochOffset      = 51
+	batchSequenceOffset   = 53
+)
+
+// encodeTxBatch encodes msgs as a transactional record batch owned by the
+// given producer, starting at sequence number seq.
+func encodeTxBatch(msgs []kafka_go.Message, producerID, epoch int, seq int32) (protocol.RawRecordSet, error) {
+	records := make([]kafka_go.Record, len(msgs))
+	for i, m := range msgs {
+		records[i] = kafka_go.Record{
+			Time:    m.Time,
+			Key:     kafka_go.NewBytes(m.Key),
+			Value:   kafka_go.NewBytes(m.Value),
+			Headers: m.Headers,
+		}
+	}
+	rs := protocol.RecordSet{Version: 2, Records: kafka_go.NewRecordReader(records...)}
+	var buf bytes.Buffer
+	if _, err := rs.WriteTo(&buf); err != nil {
+		return protocol.RawRecordSet{}, fmt.Errorf("encode record batch: %w", err)
+	}
+
+	// The record set is prefixed with its size.
+	batch := buf.Bytes()[4:]
+	attributes := binary.BigEndian.Uint16(batch[batchAttributesOffset:])
+	binary.BigEndian.PutUint16(batch[batchAttributesOffset:], attributes|uint16(protocol.Transactional))
+	binary.BigEndian.PutUint64(batch[batchProducerIDOffset:], uint64(producerID))
+	binary.BigEndian.PutUint16(batch[batchEpochOffset:], uint16(epoch))
+	binary.BigEndian.PutUint32(batch[batchSequenceOffset:], uint32(seq))
+	crc := crc32.Checksum(batch[batchAttributesOffset:], crc32.MakeTable(crc32.Castagnoli))
+	binary.BigEndian.PutUint32(batch[batchCRCOffset:], crc)
+	return protocol.RawRecordSet{Reader: &buf}, nil
+}
@@ -4,13 +4,48 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	otellog "go.opentelemetry.io/otel/log"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// TraceLevel is a level below zap's DebugLevel for very detailed
+// diagnostics. Entries at this level are written with level "trace".
+const TraceLevel = zapcore.DebugLevel - 1
+
+// levelString returns the name of lvl, including "trace" for TraceLevel.
+func levelString(lvl zapcore.Level) string {
+	if lvl == TraceLevel {
+		return "trace"
+	}
+	return lvl.String()
+}
+
+// withTraceLevel wraps a zap level encoder so that TraceLevel is encoded as
+// "trace" rather than "Level(-2)".
+func withTraceLevel(enc zapcore.LevelEncoder, color bool) zapcore.LevelEncoder {
+	return func(lvl zapcore.Level, pae zapcore.PrimitiveArrayEncoder) {
+		if lvl != TraceLevel {
+			enc(lvl, pae)
+			return
+		}
+		if color {
+			// Same color zap uses for debug
+			pae.AppendString("\x1b[35mtrace\x1b[0m")
+			return
+		}
+		pae.AppendString("trace")
+	}
+}
+
 // Field represents a key-value pair for logging.
 type Field struct {
 	Key   string
@@ -38,6 +73,12 @@ func Bool(key string, value bool) interface{} {
 	return Field{Key: key, Value: value, Type: "bool"}
 }
 
+// Duration creates a duration field, encoded as set by
+// LoggerConfig.DurationEncoding.
+func Duration(key string, value time.Duration) interface{} {
+	return Field{Key: key, Value: value, Type: "duration"}
+}
+
 // ErrField creates an error field.
 func ErrField(err error) interface{} {
 	return Field{Key: "error", Value: err, Type: "error"}
@@ -54,20 +95,126 @@ type LoggerConfig struct {
 	Output     string `mapstructure:"output" default:"console"`
 	FilePath   string `mapstructure:"file_path" default:""`
 	JSONFormat bool   `mapstructure:"json_format" default:"true"`
+	// Encoding selects "json" or "console" output. When empty, JSONFormat decides.
+	Encoding string `mapstructure:"encoding" default:""`
+	// ServiceName is added to every entry as the "service" field when set.
+	ServiceName string `mapstructure:"service_name" default:""`
+	// Type selects the logging backend. Only "zap" is supported.
+	Type string `mapstructure:"type" default:"zap"`
+	// TimeFormat is a Go time layout for the "ts" field. Defaults to ISO8601.
+	TimeFormat string `mapstructure:"time_format" default:""`
+	// FileMode sets the permissions used when creating the log file. Defaults to 0666.
+	FileMode os.FileMode `mapstructure:"file_mode" default:"0666"`
+	// FileTruncate truncates an existing log file instead of appending to it.
+	FileTruncate bool `mapstructure:"file_truncate" default:"false"`
+	// IncludeNumericLevel adds the syslog severity of each entry as "level_num".
+	IncludeNumericLevel bool `mapstructure:"include_numeric_level" default:"false"`
+	// TraceFieldsRecordingOnly adds trace_id and span_id only for spans that
+	// are recording, leaving out ids of unsampled spans.
+	TraceFieldsRecordingOnly bool `mapstructure:"trace_fields_recording_only" default:"false"`
+	// StructuredCaller replaces the "caller" field with separate
+	// "caller.file", "caller.line" and "caller.func" fields.
+	StructuredCaller bool `mapstructure:"structured_caller" default:"false"`
+	// SamplingInitial enables sampling: each second, only the first
+	// SamplingInitial entries with the same level and message are logged,
+	// then every SamplingThereafter-th one. Zero disables sampling.
+	SamplingInitial    int `mapstructure:"sampling_initial" default:"0"`
+	SamplingThereafter int `mapstructure:"sampling_thereafter" default:"0"`
+	// SamplingSummaryInterval, when sampling is enabled, logs how many
+	// entries sampling dropped per level at this interval, e.g.
+	// "suppressed 950 duplicate error logs in last 10s". Zero disables it.
+	SamplingSummaryInterval time.Duration `mapstructure:"sampling_summary_interval" default:"0"`
+	// DurationEncoding selects how duration fields are written: "string"
+	// (e.g. "1.5s", the default when empty), "seconds" or "millis" as floats,
+	// or "nanos" as an integer.
+	DurationEncoding string `mapstructure:"duration_encoding" default:"string"`
+	// Clock returns the time recorded as each entry's timestamp, including
+	// records forwarded to OpenTelemetry. Defaults to time.Now; tests can
+	// set a fixed clock for deterministic output.
+	Clock func() time.Time `mapstructure:"-"`
+	// SkipCanceledContext drops entries logged with a context that is already
+	// canceled, counting them instead (see CanceledSkipped). Fatal entries
+	// are always logged.
+	SkipCanceledContext bool `mapstructure:"skip_canceled_context" default:"false"`
+}
+
+// callerSkip is the number of frames between the user's call and the zap
+// call in logAt: the exported logging function and logAt itself.
+const callerSkip = 2
+
+// Supported values for LoggerConfig.Type.
+const TypeZap = "zap"
+
+// Supported values for LoggerConfig.Output.
+const (
+	OutputConsole = "console"
+	OutputFile    = "file"
+)
+
+// Supported values for LoggerConfig.Encoding.
+const (
+	EncodingJSON    = "json"
+	EncodingConsole = "console"
+)
+
+// Supported values for LoggerConfig.DurationEncoding.
+const (
+	DurationString  = "string"
+	DurationSeconds = "seconds"
+	DurationMillis  = "millis"
+	DurationNanos   = "nanos"
+)
+
+// durationEncoders maps LoggerConfig.DurationEncoding values to zap encoders.
+var durationEncoders = map[string]zapcore.DurationEncoder{
+	"":              zapcore.StringDurationEncoder,
+	DurationString:  zapcore.StringDurationEncoder,
+	DurationSeconds: zapcore.SecondsDurationEncoder,
+	DurationMillis:  zapcore.MillisDurationEncoder,
+	DurationNanos:   zapcore.NanosDurationEncoder,
 }
 
 var (
 	globalLogger *zap.Logger
+	baseLogger   *zap.Logger // globalLogger without the global fields
+	globalFields []Field
 	loggerMu     sync.RWMutex
 	levelCtrl    zap.AtomicLevel
+	otelLogger   otellog.Logger
+	// traceRecordingOnly mirrors LoggerConfig.TraceFieldsRecordingOnly.
+	traceRecordingOnly bool
+	// summary reports entries dropped by sampling; nil when disabled.
+	summary *samplingSummary
+	// skipCanceled mirrors LoggerConfig.SkipCanceledContext.
+	skipCanceled bool
+	// canceledSkipped counts entries dropped because of skipCanceled.
+	canceledSkipped atomic.Int64
+	// durationEncoding mirrors LoggerConfig.DurationEncoding for OTel records.
+	durationEncoding string
+	// now mirrors LoggerConfig.Clock for OTel records.
+	now = time.Now
+	// hooks are the functions registered with AddHook, guarded by hooksMu
+	// since the sampling summary writes entries without holding loggerMu.
+	hooks   []*hook
+	hooksMu sync.RWMutex
 )
 
-// Init initializes the global logger with default settings (info level, console output, JSON format).
+// LevelEnvVar is the environment variable Init and ReloadLevelFromEnv read the log level from.
+const LevelEnvVar = "CONFIG_LOGGER_LEVEL"
+
+// Init initializes the global logger with default settings (console output, JSON format).
+// The level is read from CONFIG_LOGGER_LEVEL and defaults to info; an invalid
+// value is an error.
 func Init() error {
+	level := os.Getenv(LevelEnvVar)
+	if level == "" {
+		level = "info"
+	}
 	return InitWithConfig(LoggerConfig{
-		Level:      "info",
-		Output:     "console",
+		Level:      level,
+		Output:     OutputConsole,
 		JSONFormat: true,
+		Type:       TypeZap,
 	})
 }
 
@@ -76,31 +223,59 @@ func InitWithConfig(cfg LoggerConfig) error {
 	loggerMu.Lock()
 	defer loggerMu.Unlock()
 
-	var lvl zapcore.Level
-	switch cfg.Level {
-	case "debug":
-		lvl = zapcore.DebugLevel
-	case "info":
-		lvl = zapcore.InfoLevel
-	case "warn":
-		lvl = zapcore.WarnLevel
-	case "error":
-		lvl = zapcore.ErrorLevel
-	case "fatal":
-		lvl = zapcore.FatalLevel
-	default:
-		return fmt.Errorf("invalid log level: %s", cfg.Level)
+	lvl, err := ParseLevel(cfg.Level)
+	if err != nil {
+		return err
+	}
+
+	if cfg.Type != "" && cfg.Type != TypeZap {
+		return fmt.Errorf("unsupported logger type: %s", cfg.Type)
+	}
+	if cfg.Output != "" && cfg.Output != OutputConsole && cfg.Output != OutputFile {
+		return fmt.Errorf("invalid log output: %s", cfg.Output)
+	}
+
+	encoding := cfg.Encoding
+	if encoding == "" {
+		encoding = EncodingConsole
+		if cfg.JSONFormat {
+			encoding = EncodingJSON
+		}
+	}
+	if encoding != EncodingJSON && encoding != EncodingConsole {
+		return fmt.Errorf("invalid log encoding: %s", cfg.Encoding)
+	}
+	clock := cfg.Clock
+	if clock == nil {
+		clock = time.Now
+	}
+	encodeDuration, ok := durationEncoders[cfg.DurationEncoding]
+	if !ok {
+		return fmt.Errorf("invalid duration encoding: %s", cfg.DurationEncoding)
+	}
+	if cfg.SamplingInitial < 0 || cfg.SamplingThereafter < 0 || cfg.SamplingSummaryInterval < 0 {
+		return fmt.Errorf("invalid log sampling: initial %d, thereafter %d, summary interval %s", cfg.SamplingInitial, cfg.SamplingThereafter, cfg.SamplingSummaryInterval)
 	}
 
 	var core zapcore.Core
 	var syncer zapcore.WriteSyncer
+	toFile := false
 
-	if cfg.Output == "file" && cfg.FilePath != "" {
-		file, err := os.OpenFile(cfg.FilePath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+	if cfg.Output == OutputFile && cfg.FilePath != "" {
+		flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		if cfg.FileTruncate {
+			flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+		}
+		mode := cfg.FileMode
+		if mode == 0 {
+			mode = 0666
+		}
+		file, err := os.OpenFile(cfg.FilePath, flags, mode)
 		if err != nil {
 			return fmt.Errorf("failed to open log file %s: %w", cfg.FilePath, err)
 		}
 		syncer = zapcore.AddSync(file)
+		toFile = true
 	} else {
 		syncer = zapcore.AddSync(os.Stdout)
 	}
@@ -111,26 +286,451 @@ func InitWithConfig(cfg LoggerConfig) error {
 		MessageKey:     "msg",
 		CallerKey:      "caller",
 		LineEnding:     zapcore.DefaultLineEnding,
-		EncodeLevel:    zapcore.LowercaseLevelEncoder,
+		EncodeLevel:    withTraceLevel(zapcore.LowercaseLevelEncoder, false),
 		EncodeTime:     zapcore.ISO8601TimeEncoder,
-		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeDuration: encodeDuration,
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 	}
+	if cfg.StructuredCaller {
+		encoderConfig.CallerKey = zapcore.OmitKey
+	}
+	if cfg.TimeFormat != "" {
+		encoderConfig.EncodeTime = zapcore.TimeEncoderOfLayout(cfg.TimeFormat)
+	}
 
 	levelCtrl = zap.NewAtomicLevelAt(lvl)
 
-	if cfg.JSONFormat {
+	if encoding == EncodingJSON {
 		encoder := zapcore.NewJSONEncoder(encoderConfig)
 		core = zapcore.NewCore(encoder, syncer, levelCtrl)
 	} else {
+		if !toFile {
+			// Colored levels are only useful on a terminal
+			encoderConfig.EncodeLevel = withTraceLevel(zapcore.LowercaseColorLevelEncoder, true)
+		}
 		encoder := zapcore.NewConsoleEncoder(encoderConfig)
 		core = zapcore.NewCore(encoder, syncer, levelCtrl)
 	}
+	core = &dedupCore{Core: &hookCore{Core: core}}
+	if cfg.IncludeNumericLevel {
+		core = &levelNumCore{Core: core}
+	}
+	if cfg.StructuredCaller {
+		core = &callerCore{Core: core}
+	}
+	if summary != nil {
+		summary.stop()
+		summary = nil
+	}
+	if cfg.SamplingInitial > 0 {
+		var hooks []zapcore.SamplerOption
+		var hook func(zapcore.Entry, zapcore.SamplingDecision)
+		if cfg.SamplingSummaryInterval > 0 {
+			summary = newSamplingSummary(core, cfg.SamplingSummaryInterval, clock)
+			hook = summary.record
+			hooks = append(hooks, zapcore.SamplerHook(hook))
+		}
+		core = zapcore.NewSamplerWithOptions(core, time.Second, cfg.SamplingInitial, cfg.SamplingThereafter, hooks...)
+		core = newTraceSampler(core, time.Second, cfg.SamplingInitial, cfg.SamplingThereafter, hook)
+	}
 
-	globalLogger = zap.New(core, zap.AddCaller())
+	opts := []zap.Option{zap.AddCaller(), zap.AddCallerSkip(callerSkip), zap.WithClock(funcClock(clock))}
+	if cfg.ServiceName != "" {
+		opts = append(opts, zap.Fields(zap.String("service", cfg.ServiceName)))
+	}
+	baseLogger = zap.New(core, opts...)
+	globalLogger = withGlobalFields(baseLogger)
+	traceRecordingOnly = cfg.TraceFieldsRecordingOnly
+	skipCanceled = cfg.SkipCanceledContext
+	durationEncoding = cfg.DurationEncoding
+	now = clock
 	return nil
 }
 
+// dedupCore keeps one field per key in each entry, last write wins, so that a
+// per-call field overrides a service or global field with the same key
+// instead of producing duplicate JSON keys. Fields added with With are held
+// back and merged with the entry's fields on Write.
+type dedupCore struct {
+	zapcore.Core
+	fields []zapcore.Field
+}
+
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	merged := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	merged = append(merged, c.fields...)
+	merged = append(merged, fields...)
+	return &dedupCore{Core: c.Core, fields: merged}
+}
+
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *dedupCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	all := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	all = append(all, c.fields...)
+	all = append(all, fields...)
+	return c.Core.Write(ent, dedupFields(all))
+}
+
+// dedupFields returns fields with one entry per key. A repeated key keeps the
+// position of its first occurrence and the value of its last.
+func dedupFields(fields []zapcore.Field) []zapcore.Field {
+	index := make(map[string]int, len(fields))
+	out := make([]zapcore.Field, 0, len(fields))
+	for _, f := range fields {
+		if i, ok := index[f.Key]; ok {
+			out[i] = f
+			continue
+		}
+		index[f.Key] = len(out)
+		out = append(out, f)
+	}
+	return out
+}
+
+// levelNumCore adds the syslog severity of each entry as the "level_num" field.
+type levelNumCore struct {
+	zapcore.Core
+}
+
+func (c *levelNumCore) With(fields []zapcore.Field) zapcore.Core {
+	return &levelNumCore{Core: c.Core.With(fields)}
+}
+
+func (c *levelNumCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *levelNumCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	fields = append(fields[:len(fields):len(fields)], zap.Int("level_num", syslogSeverity(ent.Level)))
+	return c.Core.Write(ent, fields)
+}
+
+// callerCore adds the caller of each entry as separate "caller.file",
+// "caller.line" and "caller.func" fields.
+type callerCore struct {
+	zapcore.Core
+}
+
+func (c *callerCore) With(fields []zapcore.Field) zapcore.Core {
+	return &callerCore{Core: c.Core.With(fields)}
+}
+
+func (c *callerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *callerCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if ent.Caller.Defined {
+		file := strings.TrimSuffix(ent.Caller.TrimmedPath(), ":"+strconv.Itoa(ent.Caller.Line))
+		fields = append(fields[:len(fields):len(fields)],
+			zap.String("caller.file", file),
+			zap.Int("caller.line", ent.Caller.Line),
+			zap.String("caller.func", ent.Caller.Function),
+		)
+	}
+	return c.Core.Write(ent, fields)
+}
+
+// LogEntry is a log entry as passed to hooks registered with AddHook.
+type LogEntry struct {
+	Level   zapcore.Level
+	Time    time.Time
+	Message string
+	// Caller is the file:line of the logging call, empty when unknown.
+	Caller string
+	// Fields holds the entry's fields, including service, global and trace
+	// fields, encoded as by the JSON encoder.
+	Fields map[string]interface{}
+}
+
+type hook struct {
+	minLevel zapcore.Level
+	fn       func(LogEntry)
+}
+
+// AddHook registers fn to be called with every entry at minLevel or above,
+// e.g. to forward warnings and errors to an alerting system. Hooks only see
+// entries enabled by the logger level and kept by sampling, and run
+// synchronously on the logging goroutine, so fn should be fast and must not
+// log itself. The returned function removes the hook.
+func AddHook(minLevel zapcore.Level, fn func(LogEntry)) func() {
+	h := &hook{minLevel: minLevel, fn: fn}
+	hooksMu.Lock()
+	hooks = append(hooks, h)
+	hooksMu.Unlock()
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+		for i, registered := range hooks {
+			if registered == h {
+				hooks = append(hooks[:i:i], hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// hookCore passes each entry written to the wrapped core to the hooks
+// registered with AddHook. It sits inside dedupCore, so it receives the
+// merged fields of the entry.
+type hookCore struct {
+	zapcore.Core
+}
+
+func (c *hookCore) With(fields []zapcore.Field) zapcore.Core {
+	return &hookCore{Core: c.Core.With(fields)}
+}
+
+func (c *hookCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+	return ce
+}
+
+func (c *hookCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	err := c.Core.Write(ent, fields)
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+	var entry *LogEntry
+	for _, h := range hooks {
+		if ent.Level < h.minLevel {
+			continue
+		}
+		if entry == nil {
+			enc := zapcore.NewMapObjectEncoder()
+			for _, f := range fields {
+				f.AddTo(enc)
+			}
+			entry = &LogEntry{Level: ent.Level, Time: ent.Time, Message: ent.Message, Fields: enc.Fields}
+			if ent.Caller.Defined {
+				entry.Caller = ent.Caller.TrimmedPath()
+			}
+		}
+		h.fn(*entry)
+	}
+	return err
+}
+
+// funcClock adapts LoggerConfig.Clock to zapcore.Clock. Tickers, used only
+// by zap internals, still follow real time.
+type funcClock func() time.Time
+
+func (c funcClock) Now() time.Time { return c() }
+
+func (c funcClock) NewTicker(d time.Duration) *time.Ticker { return time.NewTicker(d) }
+
+// traceSampler samples TraceLevel entries like zap's sampler does the other
+// levels, which it passes through unsampled since they are below DebugLevel.
+// Other entries go straight to the wrapped core.
+type traceSampler struct {
+	zapcore.Core
+	counts     *traceCounts
+	first      int
+	thereafter int
+	hook       func(zapcore.Entry, zapcore.SamplingDecision)
+}
+
+// traceCounts counts TraceLevel entries per message within the current tick.
+// It is shared by a traceSampler and the cores derived from it with With.
+type traceCounts struct {
+	mu    sync.Mutex
+	tick  time.Duration
+	reset time.Time
+	n     map[string]int
+}
+
+func newTraceSampler(core zapcore.Core, tick time.Duration, first, thereafter int, hook func(zapcore.Entry, zapcore.SamplingDecision)) *traceSampler {
+	return &traceSampler{
+		Core:       core,
+		counts:     &traceCounts{tick: tick, n: map[string]int{}},
+		first:      first,
+		thereafter: thereafter,
+		hook:       hook,
+	}
+}
+
+// inc counts ent and returns its number within the current tick.
+func (c *traceCounts) inc(ent zapcore.Entry) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !ent.Time.Before(c.reset) {
+		c.n = map[string]int{}
+		c.reset = ent.Time.Add(c.tick)
+	}
+	c.n[ent.Message]++
+	return c.n[ent.Message]
+}
+
+func (s *traceSampler) With(fields []zapcore.Field) zapcore.Core {
+	clone := *s
+	clone.Core = s.Core.With(fields)
+	return &clone
+}
+
+func (s *traceSampler) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level != TraceLevel || !s.Enabled(ent.Level) {
+		return s.Core.Check(ent, ce)
+	}
+	n := s.counts.inc(ent)
+	if n > s.first && (s.thereafter == 0 || (n-s.first)%s.thereafter != 0) {
+		if s.hook != nil {
+			s.hook(ent, zapcore.LogDropped)
+		}
+		return ce
+	}
+	if s.hook != nil {
+		s.hook(ent, zapcore.LogSampled)
+	}
+	return s.Core.Check(ent, ce)
+}
+
+// samplingSummary counts entries dropped by sampling and periodically writes
+// one summary entry per level to the unsampled core.
+type samplingSummary struct {
+	core     zapcore.Core
+	interval time.Duration
+	now      func() time.Time
+	dropped  [zapcore.FatalLevel - TraceLevel + 1]atomic.Int64
+	done     chan struct{}
+	stopped  chan struct{}
+}
+
+func newSamplingSummary(core zapcore.Core, interval time.Duration, now func() time.Time) *samplingSummary {
+	s := &samplingSummary{
+		core:     core,
+		interval: interval,
+		now:      now,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+// record is the sampler hook counting dropped entries.
+func (s *samplingSummary) record(ent zapcore.Entry, dec zapcore.SamplingDecision) {
+	if dec&zapcore.LogDropped == 0 || ent.Level < TraceLevel || ent.Level > zapcore.FatalLevel {
+		return
+	}
+	s.dropped[ent.Level-TraceLevel].Add(1)
+}
+
+func (s *samplingSummary) run() {
+	defer close(s.stopped)
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			s.flush()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// flush writes a summary entry for each level with dropped entries and
+// resets the counts.
+func (s *samplingSummary) flush() {
+	for i := range s.dropped {
+		n := s.dropped[i].Swap(0)
+		if n == 0 {
+			continue
+		}
+		lvl := TraceLevel + zapcore.Level(i)
+		ent := zapcore.Entry{
+			Level:   lvl,
+			Time:    s.now(),
+			Message: fmt.Sprintf("suppressed %d duplicate %s logs in last %s", n, levelString(lvl), s.interval),
+		}
+		_ = s.core.Write(ent, []zapcore.Field{zap.Int64("suppressed", n)})
+	}
+}
+
+// stop ends the summary goroutine and waits for it to exit.
+func (s *samplingSummary) stop() {
+	close(s.done)
+	<-s.stopped
+}
+
+// syslogSeverity maps a zap level to its syslog severity (RFC 5424), where
+// lower numbers are more severe.
+func syslogSeverity(l zapcore.Level) int {
+	switch l {
+	case TraceLevel, zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel:
+		return 2
+	case zapcore.PanicLevel:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// SetGlobalFields sets fields added to every log entry, such as version, host
+// or env. They apply to the current logger and to loggers created later by
+// Init or InitWithConfig, and replace fields from a previous call. Values that
+// are not Field are ignored.
+func SetGlobalFields(fields ...interface{}) {
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	globalFields = globalFields[:0:0]
+	for _, f := range fields {
+		if field, ok := f.(Field); ok {
+			globalFields = append(globalFields, field)
+		}
+	}
+	if baseLogger != nil {
+		globalLogger = withGlobalFields(baseLogger)
+	}
+}
+
+// withGlobalFields returns l with the global fields attached. Callers must
+// hold loggerMu.
+func withGlobalFields(l *zap.Logger) *zap.Logger {
+	if len(globalFields) == 0 {
+		return l
+	}
+	zapFields := make([]zap.Field, 0, len(globalFields))
+	for _, field := range globalFields {
+		zapFields = append(zapFields, fieldToZap(field))
+	}
+	return l.With(zapFields...)
+}
+
+// SetOTelLoggerProvider forwards every log entry to the given OpenTelemetry
+// LoggerProvider in addition to the configured output. Records carry the span
+// context of the logging call. Passing nil stops forwarding.
+func SetOTelLoggerProvider(lp otellog.LoggerProvider) {
+	loggerMu.Lock()
+	defer loggerMu.Unlock()
+	if lp == nil {
+		otelLogger = nil
+		return
+	}
+	otelLogger = lp.Logger("github.com/T-Prohmpossadhorn/go-core/logger")
+}
+
 // Sync flushes any buffered log entries.
 func Sync() error {
 	loggerMu.RLock()
@@ -148,140 +748,213 @@ func SetLevel(level string) error {
 	if globalLogger == nil {
 		return fmt.Errorf("logger not initialized")
 	}
-	var lvl zapcore.Level
-	switch level {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	levelCtrl.SetLevel(lvl)
+	return nil
+}
+
+// ReloadLevelFromEnv re-reads CONFIG_LOGGER_LEVEL and applies it at runtime.
+// An unset variable leaves the current level unchanged.
+func ReloadLevelFromEnv() error {
+	level := os.Getenv(LevelEnvVar)
+	if level == "" {
+		return nil
+	}
+	return SetLevel(level)
+}
+
+// ParseLevel converts trace, debug, info, warn, error or fatal
+// (case-insensitive) to a zap level.
+func ParseLevel(level string) (zapcore.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "trace":
+		return TraceLevel, nil
 	case "debug":
-		lvl = zapcore.DebugLevel
+		return zapcore.DebugLevel, nil
 	case "info":
-		lvl = zapcore.InfoLevel
+		return zapcore.InfoLevel, nil
 	case "warn":
-		lvl = zapcore.WarnLevel
+		return zapcore.WarnLevel, nil
 	case "error":
-		lvl = zapcore.ErrorLevel
+		return zapcore.ErrorLevel, nil
 	case "fatal":
-		lvl = zapcore.FatalLevel
+		return zapcore.FatalLevel, nil
 	default:
-		return fmt.Errorf("invalid log level: %s", level)
+		return zapcore.InfoLevel, fmt.Errorf("invalid log level: %s", level)
 	}
-	levelCtrl.SetLevel(lvl)
-	return nil
 }
 
 // GetLevel returns the current log level as a string.
 func GetLevel() string {
 	loggerMu.RLock()
 	defer loggerMu.RUnlock()
-	return levelCtrl.Level().String()
+	return levelString(levelCtrl.Level())
+}
+
+// Trace logs a trace-level message with default context.
+func Trace(msg string, fields ...interface{}) error {
+	return logAt(context.Background(), TraceLevel, msg, fields)
 }
 
 // Debug logs a debug-level message with default context.
 func Debug(msg string, fields ...interface{}) error {
-	return DebugContext(context.Background(), msg, fields...)
+	return logAt(context.Background(), zapcore.DebugLevel, msg, fields)
 }
 
 // Info logs an info-level message with default context.
 func Info(msg string, fields ...interface{}) error {
-	return InfoContext(context.Background(), msg, fields...)
+	return logAt(context.Background(), zapcore.InfoLevel, msg, fields)
 }
 
 // Warn logs a warn-level message with default context.
 func Warn(msg string, fields ...interface{}) error {
-	return WarnContext(context.Background(), msg, fields...)
+	return logAt(context.Background(), zapcore.WarnLevel, msg, fields)
 }
 
 // Error logs an error-level message with default context.
 func Error(msg string, fields ...interface{}) error {
-	return ErrorContext(context.Background(), msg, fields...)
+	return logAt(context.Background(), zapcore.ErrorLevel, msg, fields)
 }
 
 // Fatal logs a fatal-level message with default context and exits.
 func Fatal(msg string, fields ...interface{}) error {
-	return FatalContext(context.Background(), msg, fields...)
+	return logAt(context.Background(), zapcore.FatalLevel, msg, fields)
+}
+
+// TraceContext logs a trace-level message with context and fields.
+func TraceContext(ctx context.Context, msg string, fields ...interface{}) error {
+	return logAt(ctx, TraceLevel, msg, fields)
 }
 
 // DebugContext logs a debug-level message with context and fields.
 func DebugContext(ctx context.Context, msg string, fields ...interface{}) error {
-	loggerMu.RLock()
-	defer loggerMu.RUnlock()
-	if globalLogger == nil {
-		return fmt.Errorf("logger not initialized")
-	}
-	zapFields := extractTraceFields(ctx)
-	for _, f := range fields {
-		if field, ok := f.(Field); ok {
-			zapFields = append(zapFields, fieldToZap(field))
-		}
-	}
-	globalLogger.Debug(msg, zapFields...)
-	return nil
+	return logAt(ctx, zapcore.DebugLevel, msg, fields)
 }
 
 // InfoContext logs an info-level message with context and fields.
 func InfoContext(ctx context.Context, msg string, fields ...interface{}) error {
-	loggerMu.RLock()
-	defer loggerMu.RUnlock()
-	if globalLogger == nil {
-		return fmt.Errorf("logger not initialized")
-	}
-	zapFields := extractTraceFields(ctx)
-	for _, f := range fields {
-		if field, ok := f.(Field); ok {
-			zapFields = append(zapFields, fieldToZap(field))
-		}
-	}
-	globalLogger.Info(msg, zapFields...)
-	return nil
+	return logAt(ctx, zapcore.InfoLevel, msg, fields)
 }
 
 // WarnContext logs a warn-level message with context and fields.
 func WarnContext(ctx context.Context, msg string, fields ...interface{}) error {
-	loggerMu.RLock()
-	defer loggerMu.RUnlock()
-	if globalLogger == nil {
-		return fmt.Errorf("logger not initialized")
-	}
-	zapFields := extractTraceFields(ctx)
-	for _, f := range fields {
-		if field, ok := f.(Field); ok {
-			zapFields = append(zapFields, fieldToZap(field))
-		}
-	}
-	globalLogger.Warn(msg, zapFields...)
-	return nil
+	return logAt(ctx, zapcore.WarnLevel, msg, fields)
 }
 
 // ErrorContext logs an error-level message with context and fields.
 func ErrorContext(ctx context.Context, msg string, fields ...interface{}) error {
+	return logAt(ctx, zapcore.ErrorLevel, msg, fields)
+}
+
+// FatalContext logs a fatal-level message with context and fields, then exits.
+func FatalContext(ctx context.Context, msg string, fields ...interface{}) error {
+	return logAt(ctx, zapcore.FatalLevel, msg, fields)
+}
+
+// Tracef formats a trace-level message with fmt.Sprintf and logs it with
+// TraceContext.
+func Tracef(ctx context.Context, format string, args ...interface{}) error {
+	return logAt(ctx, TraceLevel, fmt.Sprintf(format, args...), nil)
+}
+
+// Debugf formats a debug-level message with fmt.Sprintf and logs it with
+// DebugContext, so trace ids and base fields are still attached.
+func Debugf(ctx context.Context, format string, args ...interface{}) error {
+	return logAt(ctx, zapcore.DebugLevel, fmt.Sprintf(format, args...), nil)
+}
+
+// Infof formats an info-level message with fmt.Sprintf and logs it with
+// InfoContext.
+func Infof(ctx context.Context, format string, args ...interface{}) error {
+	return logAt(ctx, zapcore.InfoLevel, fmt.Sprintf(format, args...), nil)
+}
+
+// Warnf formats a warn-level message with fmt.Sprintf and logs it with
+// WarnContext.
+func Warnf(ctx context.Context, format string, args ...interface{}) error {
+	return logAt(ctx, zapcore.WarnLevel, fmt.Sprintf(format, args...), nil)
+}
+
+// Errorf formats an error-level message with fmt.Sprintf and logs it with
+// ErrorContext.
+func Errorf(ctx context.Context, format string, args ...interface{}) error {
+	return logAt(ctx, zapcore.ErrorLevel, fmt.Sprintf(format, args...), nil)
+}
+
+// logAt writes an entry at lvl with the trace fields from ctx and fields.
+// Every exported logging function calls it directly, so the caller reported
+// by zap is always callerSkip frames above it.
+func logAt(ctx context.Context, lvl zapcore.Level, msg string, fields []interface{}) error {
 	loggerMu.RLock()
 	defer loggerMu.RUnlock()
 	if globalLogger == nil {
 		return fmt.Errorf("logger not initialized")
 	}
+	if skipCanceled && lvl < zapcore.FatalLevel && ctx.Err() != nil {
+		canceledSkipped.Add(1)
+		return nil
+	}
 	zapFields := extractTraceFields(ctx)
 	for _, f := range fields {
 		if field, ok := f.(Field); ok {
 			zapFields = append(zapFields, fieldToZap(field))
 		}
 	}
-	globalLogger.Error(msg, zapFields...)
+	if ce := globalLogger.Check(lvl, msg); ce != nil {
+		// Check applies sampling, so OpenTelemetry receives the same entries
+		// as the configured output.
+		emitOTel(ctx, lvl, msg, fields)
+		ce.Write(zapFields...)
+	}
 	return nil
 }
 
-// FatalContext logs a fatal-level message with context and fields, then exits.
-func FatalContext(ctx context.Context, msg string, fields ...interface{}) error {
-	loggerMu.RLock()
-	defer loggerMu.RUnlock()
-	if globalLogger == nil {
-		return fmt.Errorf("logger not initialized")
+// CanceledSkipped returns how many entries have been dropped because their
+// context was canceled while LoggerConfig.SkipCanceledContext was set.
+func CanceledSkipped() int64 {
+	return canceledSkipped.Load()
+}
+
+// RecoverOption configures Recover.
+type RecoverOption func(*recoverConfig)
+
+type recoverConfig struct {
+	repanic bool
+}
+
+// WithRepanic makes Recover panic again with the original value after logging
+// it, so the panic still crashes the program or reaches an outer recover.
+func WithRepanic() RecoverOption {
+	return func(c *recoverConfig) {
+		c.repanic = true
 	}
-	zapFields := extractTraceFields(ctx)
-	for _, f := range fields {
-		if field, ok := f.(Field); ok {
-			zapFields = append(zapFields, fieldToZap(field))
+}
+
+// Recover returns a function that recovers a panic and logs its value and
+// stack trace at error level. It must be deferred directly:
+//
+//	defer logger.Recover(ctx)()
+func Recover(ctx context.Context, opts ...RecoverOption) func() {
+	cfg := &recoverConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return func() {
+		p := recover()
+		if p == nil {
+			return
+		}
+		_ = ErrorContext(ctx, "Recovered from panic",
+			String("panic", fmt.Sprint(p)),
+			String("stack", string(debug.Stack())),
+		)
+		if cfg.repanic {
+			panic(p)
 		}
 	}
-	globalLogger.Fatal(msg, zapFields...)
-	return nil
 }
 
 // fieldToZap converts a Field to a zap.Field.
@@ -301,6 +974,10 @@ func fieldToZap(field Field) zap.Field {
 		if v, ok := field.Value.(bool); ok {
 			return zap.Bool(field.Key, v)
 		}
+	case "duration":
+		if v, ok := field.Value.(time.Duration); ok {
+			return zap.Duration(field.Key, v)
+		}
 	case "error":
 		if err, ok := field.Value.(error); ok && err != nil {
 			return zap.Error(err)
@@ -311,10 +988,83 @@ func fieldToZap(field Field) zap.Field {
 	return zap.Any(field.Key, field.Value)
 }
 
+// emitOTel forwards a log entry to the OpenTelemetry logger, if one is set.
+func emitOTel(ctx context.Context, lvl zapcore.Level, msg string, fields []interface{}) {
+	if otelLogger == nil || !levelCtrl.Enabled(lvl) {
+		return
+	}
+	var rec otellog.Record
+	rec.SetTimestamp(now())
+	rec.SetBody(otellog.StringValue(msg))
+	rec.SetSeverity(otelSeverity(lvl))
+	rec.SetSeverityText(levelString(lvl))
+	for _, field := range globalFields {
+		rec.AddAttributes(fieldToOTel(field))
+	}
+	for _, f := range fields {
+		if field, ok := f.(Field); ok {
+			rec.AddAttributes(fieldToOTel(field))
+		}
+	}
+	otelLogger.Emit(ctx, rec)
+}
+
+// otelSeverity maps a zap level to an OpenTelemetry severity.
+func otelSeverity(lvl zapcore.Level) otellog.Severity {
+	switch lvl {
+	case TraceLevel:
+		return otellog.SeverityTrace
+	case zapcore.DebugLevel:
+		return otellog.SeverityDebug
+	case zapcore.InfoLevel:
+		return otellog.SeverityInfo
+	case zapcore.WarnLevel:
+		return otellog.SeverityWarn
+	case zapcore.ErrorLevel:
+		return otellog.SeverityError
+	default:
+		return otellog.SeverityFatal
+	}
+}
+
+// fieldToOTel converts a Field to an OpenTelemetry log attribute.
+func fieldToOTel(field Field) otellog.KeyValue {
+	switch v := field.Value.(type) {
+	case string:
+		return otellog.String(field.Key, v)
+	case int:
+		return otellog.Int(field.Key, v)
+	case float64:
+		return otellog.Float64(field.Key, v)
+	case bool:
+		return otellog.Bool(field.Key, v)
+	case error:
+		return otellog.String(field.Key, v.Error())
+	case time.Duration:
+		return durationToOTel(field.Key, v)
+	}
+	return otellog.String(field.Key, fmt.Sprint(field.Value))
+}
+
+// durationToOTel encodes d like the zap encoder selected by durationEncoding.
+func durationToOTel(key string, d time.Duration) otellog.KeyValue {
+	switch durationEncoding {
+	case DurationSeconds:
+		return otellog.Float64(key, d.Seconds())
+	case DurationMillis:
+		return otellog.Float64(key, float64(d)/float64(time.Millisecond))
+	case DurationNanos:
+		return otellog.Int64(key, int64(d))
+	}
+	return otellog.String(key, d.String())
+}
+
 // extractTraceFields extracts OpenTelemetry trace fields from the context.
+// With TraceFieldsRecordingOnly, spans that are not recording are skipped.
+// Callers must hold loggerMu.
 func extractTraceFields(ctx context.Context) []zap.Field {
 	span := trace.SpanFromContext(ctx)
-	if !span.SpanContext().IsValid() {
+	if !span.SpanContext().IsValid() || (traceRecordingOnly && !span.IsRecording()) {
 		return nil
 	}
 	return []zap.Field{
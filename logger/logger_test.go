@@ -15,6 +15,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/log/logtest"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	oteltrace "go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
@@ -844,6 +845,154 @@ func TestSetLevel(t *testing.T) {
 	assert.Contains(t, out, "after")
 }
 
+// TestTraceLevel verifies trace is parsed, filtered below debug and encoded as "trace".
+func TestTraceLevel(t *testing.T) {
+	lvl, err := ParseLevel("TRACE")
+	assert.NoError(t, err)
+	assert.Equal(t, TraceLevel, lvl)
+	assert.True(t, lvl < zapcore.DebugLevel)
+
+	originalStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err = InitWithConfig(LoggerConfig{Level: "debug", Output: "console", JSONFormat: true})
+	assert.NoError(t, err)
+	_ = Trace("hidden trace")
+	_ = Debug("visible debug")
+
+	assert.NoError(t, SetLevel("trace"))
+	assert.Equal(t, "trace", GetLevel())
+	_ = TraceContext(context.Background(), "visible trace", String("key", "value"))
+	_ = Tracef(context.Background(), "formatted %d", 42)
+	_ = Sync()
+
+	w.Close()
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(r)
+	assert.NoError(t, err)
+	os.Stdout = originalStdout
+	out := buf.String()
+
+	assert.NotContains(t, out, "hidden trace")
+	assert.Contains(t, out, "visible debug")
+	var entry map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		var e map[string]interface{}
+		if json.Unmarshal([]byte(line), &e) == nil && e["msg"] == "visible trace" {
+			entry = e
+		}
+	}
+	assert.NotNil(t, entry)
+	assert.Equal(t, "trace", entry["level"])
+	assert.Equal(t, "value", entry["key"])
+	assert.Contains(t, out, "formatted 42")
+}
+
+// TestConsoleEncoding verifies the console encoding emits human-readable, non-JSON lines.
+func TestConsoleEncoding(t *testing.T) {
+	originalStdout := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+
+	err := InitWithConfig(LoggerConfig{
+		Level:      "info",
+		Output:     "console",
+		JSONFormat: true,
+		Encoding:   EncodingConsole,
+	})
+	assert.NoError(t, err)
+	_ = Info("console message", String("key", "value"))
+	_ = Sync()
+
+	w.Close()
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(r)
+	assert.NoError(t, err)
+	os.Stdout = originalStdout
+
+	line := strings.TrimSpace(buf.String())
+	assert.Contains(t, line, "console message")
+	assert.Contains(t, line, `"key": "value"`)
+	var entry map[string]interface{}
+	assert.Error(t, json.Unmarshal([]byte(line), &entry), "console output should not be JSON")
+}
+
+// TestInvalidEncoding verifies unknown encodings are rejected.
+func TestInvalidEncoding(t *testing.T) {
+	err := InitWithConfig(LoggerConfig{Level: "info", Encoding: "xml"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid log encoding")
+}
+
+// TestInitWithConfigOptions verifies service name, time format and file options are applied.
+func TestInitWithConfigOptions(t *testing.T) {
+	path := t.TempDir() + "/service.log"
+	assert.NoError(t, os.WriteFile(path, []byte("stale\n"), 0600))
+
+	err := InitWithConfig(LoggerConfig{
+		Level:        "info",
+		Output:       OutputFile,
+		FilePath:     path,
+		JSONFormat:   true,
+		ServiceName:  "orders",
+		Type:         TypeZap,
+		TimeFormat:   time.RFC3339,
+		FileTruncate: true,
+	})
+	assert.NoError(t, err)
+	_ = Info("service message")
+	assert.NoError(t, Sync())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "stale")
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(bytes.TrimSpace(content), &entry))
+	assert.Equal(t, "orders", entry["service"])
+	assert.Equal(t, "service message", entry["msg"])
+	_, err = time.Parse(time.RFC3339, entry["ts"].(string))
+	assert.NoError(t, err)
+}
+
+// TestInitWithConfigValidation verifies unsupported types and outputs are rejected.
+func TestInitWithConfigValidation(t *testing.T) {
+	err := InitWithConfig(LoggerConfig{Level: "info", Type: "logrus"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unsupported logger type")
+
+	err = InitWithConfig(LoggerConfig{Level: "info", Output: "syslog"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid log output")
+}
+
+// TestInitLevelFromEnv verifies Init reads CONFIG_LOGGER_LEVEL and rejects invalid values.
+func TestInitLevelFromEnv(t *testing.T) {
+	t.Setenv(LevelEnvVar, "verbose")
+	err := Init()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid log level")
+
+	t.Setenv(LevelEnvVar, "WARN")
+	assert.NoError(t, Init())
+	assert.Equal(t, "warn", GetLevel())
+}
+
+// TestReloadLevelFromEnv verifies the level can be re-read at runtime.
+func TestReloadLevelFromEnv(t *testing.T) {
+	t.Setenv(LevelEnvVar, "info")
+	assert.NoError(t, Init())
+
+	t.Setenv(LevelEnvVar, "debug")
+	assert.NoError(t, ReloadLevelFromEnv())
+	assert.Equal(t, "debug", GetLevel())
+
+	t.Setenv(LevelEnvVar, "bogus")
+	assert.Error(t, ReloadLevelFromEnv())
+	assert.Equal(t, "debug", GetLevel())
+}
+
 // performTestLogging executes a set of logging operations for testing.
 func performTestLogging(t *testing.T, ctx context.Context) {
 	err := InfoContext(ctx, "Test message",
@@ -874,3 +1023,578 @@ func performTestLogging(t *testing.T, ctx context.Context) {
 	err = Error("Error message", String("error_field", "error"))
 	assert.NoError(t, err)
 }
+
+// TestFormattedLogging verifies the printf-style wrappers format the message
+// and keep trace ids and base fields.
+func TestFormattedLogging(t *testing.T) {
+	path := t.TempDir() + "/formatted.log"
+	err := InitWithConfig(LoggerConfig{
+		Level:       "debug",
+		Output:      OutputFile,
+		FilePath:    path,
+		JSONFormat:  true,
+		ServiceName: "orders",
+	})
+	assert.NoError(t, err)
+
+	traceID, _ := oteltrace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanID, _ := oteltrace.SpanIDFromHex("0102030405060708")
+	ctx := oteltrace.ContextWithSpanContext(context.Background(), oteltrace.NewSpanContext(oteltrace.SpanContextConfig{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: oteltrace.FlagsSampled,
+	}))
+
+	assert.NoError(t, Infof(ctx, "x=%d", 5))
+	assert.NoError(t, Debugf(ctx, "debug %s", "msg"))
+	assert.NoError(t, Warnf(ctx, "warn %v", true))
+	assert.NoError(t, Errorf(ctx, "error %q", "boom"))
+	assert.NoError(t, Sync())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 4)
+
+	expected := []string{"x=5", "debug msg", "warn true", `error "boom"`}
+	for i, line := range lines {
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		assert.Equal(t, expected[i], entry["msg"])
+		assert.Equal(t, traceID.String(), entry["trace_id"])
+		assert.Equal(t, "orders", entry["service"])
+	}
+}
+
+// TestRecover verifies panics are logged with a stack trace and optionally
+// re-raised.
+func TestRecover(t *testing.T) {
+	path := t.TempDir() + "/recover.log"
+	err := InitWithConfig(LoggerConfig{
+		Level:      "info",
+		Output:     OutputFile,
+		FilePath:   path,
+		JSONFormat: true,
+	})
+	assert.NoError(t, err)
+
+	func() {
+		defer Recover(context.Background())()
+		panicInWorker()
+	}()
+
+	assert.PanicsWithValue(t, "again", func() {
+		defer Recover(context.Background(), WithRepanic())()
+		panic("again")
+	})
+	assert.NoError(t, Sync())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 2)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.Equal(t, "error", entry["level"])
+	assert.Equal(t, "Recovered from panic", entry["msg"])
+	assert.Equal(t, "worker failed", entry["panic"])
+	assert.Contains(t, entry["stack"], "panicInWorker")
+}
+
+func panicInWorker() {
+	panic("worker failed")
+}
+
+// TestSetGlobalFields verifies global fields appear on loggers created after
+// the call and on the current logger when changed.
+func TestSetGlobalFields(t *testing.T) {
+	SetGlobalFields(String("version", "1.2.3"), String("env", "prod"))
+	defer SetGlobalFields()
+
+	path := t.TempDir() + "/global.log"
+	err := InitWithConfig(LoggerConfig{
+		Level:      "info",
+		Output:     OutputFile,
+		FilePath:   path,
+		JSONFormat: true,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, Info("first", String("key", "value")))
+
+	SetGlobalFields(String("version", "1.2.4"))
+	assert.NoError(t, InfoContext(context.Background(), "second"))
+	assert.NoError(t, Sync())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 2)
+
+	var first, second map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &first))
+	assert.Equal(t, "1.2.3", first["version"])
+	assert.Equal(t, "prod", first["env"])
+	assert.Equal(t, "value", first["key"])
+
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &second))
+	assert.Equal(t, "1.2.4", second["version"])
+	_, hasEnv := second["env"]
+	assert.False(t, hasEnv, "replaced global fields should not linger")
+}
+
+// TestIncludeNumericLevel tests the syslog severity added as level_num and
+// that it is omitted by default.
+func TestIncludeNumericLevel(t *testing.T) {
+	path := t.TempDir() + "/levels.log"
+	err := InitWithConfig(LoggerConfig{
+		Level:               "debug",
+		Output:              OutputFile,
+		FilePath:            path,
+		JSONFormat:          true,
+		IncludeNumericLevel: true,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, Debug("debug"))
+	assert.NoError(t, Info("info", String("key", "value")))
+	assert.NoError(t, Warn("warn"))
+	assert.NoError(t, Error("error"))
+	assert.NoError(t, Sync())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 4)
+
+	want := []struct {
+		level string
+		num   float64
+	}{{"debug", 7}, {"info", 6}, {"warn", 4}, {"error", 3}}
+	for i, line := range lines {
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		assert.Equal(t, want[i].level, entry["level"])
+		assert.Equal(t, want[i].num, entry["level_num"])
+	}
+
+	// Disabled by default
+	assert.NoError(t, InitWithConfig(LoggerConfig{Level: "info", Output: OutputFile, FilePath: path, JSONFormat: true, FileTruncate: true}))
+	assert.NoError(t, Info("plain"))
+	assert.NoError(t, Sync())
+	content, err = os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "level_num")
+}
+
+// TestDuplicateFieldKeys tests that a repeated field key is written once, with
+// the last value winning over global fields and earlier call fields.
+func TestDuplicateFieldKeys(t *testing.T) {
+	SetGlobalFields(String("env", "prod"))
+	defer SetGlobalFields()
+
+	path := t.TempDir() + "/dedup.log"
+	err := InitWithConfig(LoggerConfig{
+		Level:       "info",
+		Output:      OutputFile,
+		FilePath:    path,
+		JSONFormat:  true,
+		ServiceName: "orders",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, Info("dup", String("env", "staging"), String("attempt", "1"), String("attempt", "2")))
+	assert.NoError(t, Sync())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	line := strings.TrimSpace(string(content))
+	assert.Equal(t, 1, strings.Count(line, `"env":`), line)
+	assert.Equal(t, 1, strings.Count(line, `"attempt":`), line)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+	assert.Equal(t, "staging", entry["env"])
+	assert.Equal(t, "2", entry["attempt"])
+	assert.Equal(t, "orders", entry["service"])
+}
+
+// TestTraceFieldsRecordingOnly tests that trace fields of an unsampled span are
+// omitted only when TraceFieldsRecordingOnly is set.
+func TestTraceFieldsRecordingOnly(t *testing.T) {
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSampler(sdktrace.NeverSample()))
+	defer tp.Shutdown(context.Background())
+	ctx, span := tp.Tracer("test-logger").Start(context.Background(), "unsampled")
+	defer span.End()
+	assert.True(t, span.SpanContext().IsValid())
+	assert.False(t, span.IsRecording())
+
+	for _, recordingOnly := range []bool{false, true} {
+		path := t.TempDir() + "/trace.log"
+		err := InitWithConfig(LoggerConfig{
+			Level:                    "info",
+			Output:                   OutputFile,
+			FilePath:                 path,
+			JSONFormat:               true,
+			TraceFieldsRecordingOnly: recordingOnly,
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, InfoContext(ctx, "unsampled span"))
+		assert.NoError(t, Sync())
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal(content, &entry))
+		_, hasTrace := entry["trace_id"]
+		_, hasSpan := entry["span_id"]
+		assert.Equal(t, !recordingOnly, hasTrace, "recordingOnly=%v", recordingOnly)
+		assert.Equal(t, !recordingOnly, hasSpan, "recordingOnly=%v", recordingOnly)
+	}
+	assert.NoError(t, InitWithConfig(LoggerConfig{Level: "info", Output: OutputConsole, JSONFormat: true}))
+}
+
+// TestStructuredCaller tests the caller split into file, line and function
+// fields.
+func TestStructuredCaller(t *testing.T) {
+	path := t.TempDir() + "/caller.log"
+	err := InitWithConfig(LoggerConfig{
+		Level:            "info",
+		Output:           OutputFile,
+		FilePath:         path,
+		JSONFormat:       true,
+		StructuredCaller: true,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, Info("structured caller"))
+	assert.NoError(t, Sync())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(content, &entry))
+	assert.NotContains(t, entry, "caller")
+	assert.Equal(t, "logger/logger_test.go", entry["caller.file"])
+	assert.Greater(t, entry["caller.line"], float64(0))
+	assert.Equal(t, "github.com/T-Prohmpossadhorn/go-core/logger.TestStructuredCaller", entry["caller.func"])
+
+	assert.NoError(t, InitWithConfig(LoggerConfig{Level: "info", Output: OutputConsole, JSONFormat: true}))
+}
+
+// TestSamplingSummary tests that sampled-out entries are reported by periodic
+// summaries at their level.
+func TestSamplingSummary(t *testing.T) {
+	path := t.TempDir() + "/sampling.log"
+	err := InitWithConfig(LoggerConfig{
+		Level:                   "info",
+		Output:                  OutputFile,
+		FilePath:                path,
+		JSONFormat:              true,
+		SamplingInitial:         1,
+		SamplingSummaryInterval: 50 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, InitWithConfig(LoggerConfig{Level: "info", Output: OutputConsole, JSONFormat: true}))
+	}()
+
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, Error("database unavailable"))
+	}
+
+	// Sampling keeps the first entry per second; the rest are summarized.
+	// Count both so a second boundary during the loop cannot break the test.
+	var logged, suppressed int
+	var summaries []map[string]interface{}
+	assert.Eventually(t, func() bool {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return false
+		}
+		logged, suppressed, summaries = 0, 0, nil
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			var entry map[string]interface{}
+			if json.Unmarshal([]byte(line), &entry) != nil {
+				continue
+			}
+			switch msg := entry["msg"].(string); {
+			case msg == "database unavailable":
+				logged++
+			case strings.HasPrefix(msg, "suppressed "):
+				summaries = append(summaries, entry)
+				n, _ := entry["suppressed"].(float64)
+				suppressed += int(n)
+			}
+		}
+		return logged+suppressed == 100
+	}, 2*time.Second, 20*time.Millisecond)
+
+	assert.NotEmpty(t, summaries)
+	for _, entry := range summaries {
+		assert.Equal(t, "error", entry["level"])
+		assert.Equal(t, fmt.Sprintf("suppressed %v duplicate error logs in last 50ms", entry["suppressed"]), entry["msg"])
+	}
+	if len(summaries) == 1 && logged == 1 {
+		assert.Equal(t, "suppressed 99 duplicate error logs in last 50ms", summaries[0]["msg"])
+	}
+}
+
+// TestSamplingSummaryTraceLevel tests that trace entries are sampled and
+// summarized like the other levels.
+func TestSamplingSummaryTraceLevel(t *testing.T) {
+	path := t.TempDir() + "/sampling-trace.log"
+	err := InitWithConfig(LoggerConfig{
+		Level:                   "trace",
+		Output:                  OutputFile,
+		FilePath:                path,
+		JSONFormat:              true,
+		SamplingInitial:         1,
+		SamplingSummaryInterval: 50 * time.Millisecond,
+	})
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, InitWithConfig(LoggerConfig{Level: "info", Output: OutputConsole, JSONFormat: true}))
+	}()
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, Trace("cache miss"))
+	}
+
+	var logged, suppressed int
+	assert.Eventually(t, func() bool {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return false
+		}
+		logged, suppressed = 0, 0
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			var entry map[string]interface{}
+			if json.Unmarshal([]byte(line), &entry) != nil {
+				continue
+			}
+			switch msg := entry["msg"].(string); {
+			case msg == "cache miss":
+				logged++
+			case strings.HasPrefix(msg, "suppressed "):
+				assert.Equal(t, "trace", entry["level"])
+				assert.Equal(t, fmt.Sprintf("suppressed %v duplicate trace logs in last 50ms", entry["suppressed"]), msg)
+				n, _ := entry["suppressed"].(float64)
+				suppressed += int(n)
+			}
+		}
+		return logged+suppressed == 10
+	}, 2*time.Second, 20*time.Millisecond)
+	assert.Greater(t, suppressed, 0, "trace entries should be sampled and summarized")
+}
+
+// TestSamplingOTel tests that only entries kept by sampling are forwarded to
+// OpenTelemetry.
+func TestSamplingOTel(t *testing.T) {
+	rec := logtest.NewRecorder()
+	SetOTelLoggerProvider(rec)
+	defer SetOTelLoggerProvider(nil)
+	path := t.TempDir() + "/sampling-otel.log"
+	err := InitWithConfig(LoggerConfig{
+		Level:           "info",
+		Output:          OutputFile,
+		FilePath:        path,
+		JSONFormat:      true,
+		SamplingInitial: 1,
+	})
+	assert.NoError(t, err)
+	defer func() {
+		assert.NoError(t, InitWithConfig(LoggerConfig{Level: "info", Output: OutputConsole, JSONFormat: true}))
+	}()
+
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, Error("database unavailable"))
+	}
+	assert.NoError(t, Sync())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	logged := strings.Count(string(content), "database unavailable")
+	var emitted int
+	for _, scope := range rec.Result() {
+		emitted += len(scope.Records)
+	}
+	assert.Less(t, logged, 100)
+	assert.Equal(t, logged, emitted)
+}
+
+// TestSamplingInvalidConfig tests initialization with a negative sampling
+// setting.
+func TestSamplingInvalidConfig(t *testing.T) {
+	err := InitWithConfig(LoggerConfig{Level: "info", Output: OutputConsole, SamplingInitial: -1})
+	assert.ErrorContains(t, err, "invalid log sampling")
+}
+
+// TestSkipCanceledContext tests that entries with a canceled context are
+// dropped and counted only when SkipCanceledContext is set.
+func TestSkipCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, skip := range []bool{false, true} {
+		path := t.TempDir() + "/canceled.log"
+		err := InitWithConfig(LoggerConfig{
+			Level:               "info",
+			Output:              OutputFile,
+			FilePath:            path,
+			JSONFormat:          true,
+			SkipCanceledContext: skip,
+		})
+		assert.NoError(t, err)
+		before := CanceledSkipped()
+		assert.NoError(t, InfoContext(ctx, "during shutdown"))
+		assert.NoError(t, Errorf(ctx, "failed %d", 1))
+		assert.NoError(t, InfoContext(context.Background(), "still logged"))
+		assert.NoError(t, Sync())
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		out := string(content)
+		assert.Contains(t, out, "still logged")
+		if skip {
+			assert.NotContains(t, out, "during shutdown")
+			assert.NotContains(t, out, "failed 1")
+			assert.Equal(t, before+2, CanceledSkipped())
+		} else {
+			assert.Contains(t, out, "during shutdown")
+			assert.Contains(t, out, "failed 1")
+			assert.Equal(t, before, CanceledSkipped())
+		}
+	}
+	assert.NoError(t, InitWithConfig(LoggerConfig{Level: "info", Output: OutputConsole, JSONFormat: true}))
+}
+
+// TestAddHook tests that a hook receives entries at or above its level with
+// their fields and stops after removal.
+func TestAddHook(t *testing.T) {
+	var mu sync.Mutex
+	var captured []LogEntry
+	remove := AddHook(zapcore.ErrorLevel, func(e LogEntry) {
+		mu.Lock()
+		defer mu.Unlock()
+		captured = append(captured, e)
+	})
+	defer remove()
+
+	// Hooks registered before InitWithConfig apply to the new logger
+	path := t.TempDir() + "/hook.log"
+	err := InitWithConfig(LoggerConfig{
+		Level:       "debug",
+		Output:      OutputFile,
+		FilePath:    path,
+		JSONFormat:  true,
+		ServiceName: "orders",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, Debug("debug entry"))
+	assert.NoError(t, Info("info entry"))
+	assert.NoError(t, Warn("warn entry"))
+	assert.NoError(t, Error("payment failed", String("order_id", "42"), ErrField(errors.New("card declined"))))
+
+	mu.Lock()
+	assert.Len(t, captured, 1)
+	entry := captured[0]
+	mu.Unlock()
+	assert.Equal(t, zapcore.ErrorLevel, entry.Level)
+	assert.Equal(t, "payment failed", entry.Message)
+	assert.Contains(t, entry.Caller, "logger_test.go")
+	assert.False(t, entry.Time.IsZero())
+	assert.Equal(t, "42", entry.Fields["order_id"])
+	assert.Equal(t, "card declined", entry.Fields["error"])
+	assert.Equal(t, "orders", entry.Fields["service"])
+
+	// The entry is still written to the configured output
+	assert.NoError(t, Sync())
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "payment failed")
+
+	remove()
+	assert.NoError(t, Error("after removal"))
+	mu.Lock()
+	assert.Len(t, captured, 1)
+	mu.Unlock()
+	assert.NoError(t, InitWithConfig(LoggerConfig{Level: "info", Output: OutputConsole, JSONFormat: true}))
+}
+
+// TestDurationEncoding tests each duration encoding in JSON output and OTel
+// records, and rejects an unknown one.
+func TestDurationEncoding(t *testing.T) {
+	for _, tc := range []struct {
+		encoding string
+		want     interface{}
+	}{
+		{"", "1.5s"},
+		{DurationString, "1.5s"},
+		{DurationSeconds, 1.5},
+		{DurationMillis, float64(1500)},
+		{DurationNanos, float64(1500000000)},
+	} {
+		path := t.TempDir() + "/duration.log"
+		err := InitWithConfig(LoggerConfig{
+			Level:            "info",
+			Output:           OutputFile,
+			FilePath:         path,
+			JSONFormat:       true,
+			DurationEncoding: tc.encoding,
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, Info("request done", Duration("elapsed", 1500*time.Millisecond)))
+		assert.NoError(t, Sync())
+
+		content, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal(content, &entry))
+		assert.Equal(t, tc.want, entry["elapsed"], tc.encoding)
+
+		// OTel records use the same representation
+		kv := fieldToOTel(Field{Key: "elapsed", Value: 1500 * time.Millisecond})
+		switch want := tc.want.(type) {
+		case string:
+			assert.Equal(t, want, kv.Value.AsString(), tc.encoding)
+		default:
+			if tc.encoding == DurationNanos {
+				assert.Equal(t, int64(1500000000), kv.Value.AsInt64())
+			} else {
+				assert.Equal(t, want, kv.Value.AsFloat64(), tc.encoding)
+			}
+		}
+	}
+
+	err := InitWithConfig(LoggerConfig{Level: "info", DurationEncoding: "hours"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid duration encoding")
+	assert.NoError(t, InitWithConfig(LoggerConfig{Level: "info", Output: OutputConsole, JSONFormat: true}))
+}
+
+// TestClock tests that entry timestamps come from LoggerConfig.Clock.
+func TestClock(t *testing.T) {
+	fixed := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	path := t.TempDir() + "/clock.log"
+	err := InitWithConfig(LoggerConfig{
+		Level:      "info",
+		Output:     OutputFile,
+		FilePath:   path,
+		JSONFormat: true,
+		Clock:      func() time.Time { return fixed },
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, Info("first"))
+	time.Sleep(5 * time.Millisecond)
+	assert.NoError(t, Warn("second"))
+	assert.NoError(t, Sync())
+
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	assert.Len(t, lines, 2)
+	for _, line := range lines {
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		assert.Equal(t, "2024-01-02T03:04:05.000Z", entry["ts"])
+	}
+	assert.NoError(t, InitWithConfig(LoggerConfig{Level: "info", Output: OutputConsole, JSONFormat: true}))
+}
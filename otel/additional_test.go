@@ -1,8 +1,22 @@
 package otel
 
 import (
-	"github.com/T-Prohmpossadhorn/go-core/config"
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/T-Prohmpossadhorn/go-core/config"
+	"github.com/T-Prohmpossadhorn/go-core/logger"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 // TestInitDisabled ensures Init respects disabled config.
@@ -34,3 +48,446 @@ func TestValidateEndpoint(t *testing.T) {
 		t.Fatal("expected invalid port error")
 	}
 }
+
+// TestLogsExportedWithTraceID ensures logger output reaches the log exporter with span context.
+func TestLogsExportedWithTraceID(t *testing.T) {
+	if err := logger.Init(); err != nil {
+		t.Fatalf("logger init: %v", err)
+	}
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"otel_enabled":      true,
+		"otel_logs_enabled": true,
+		"otel_endpoint":     "localhost:4317",
+	}))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	exp := logExporter.(*mockLogExporter)
+	defer Shutdown(context.Background())
+
+	ctx, span := StartSpan(context.Background(), "test", "log")
+	_ = logger.InfoContext(ctx, "exported message", logger.String("k", "v"))
+	span.End()
+
+	exp.mu.Lock()
+	defer exp.mu.Unlock()
+	for _, r := range exp.records {
+		if r.Body().AsString() == "exported message" {
+			if r.TraceID() != span.SpanContext().TraceID() {
+				t.Fatalf("expected trace id %s, got %s", span.SpanContext().TraceID(), r.TraceID())
+			}
+			return
+		}
+	}
+	t.Fatal("log record not exported")
+}
+
+// TestReinitShutsDownLoggerProvider ensures a second init, enabled or not,
+// shuts down the previous LoggerProvider and stops forwarding logs to it.
+func TestReinitShutsDownLoggerProvider(t *testing.T) {
+	if err := logger.Init(); err != nil {
+		t.Fatalf("logger init: %v", err)
+	}
+	logsCfg := OTelConfig{Enabled: true, LogsEnabled: true, Exporter: ExporterOTLP, Endpoint: "localhost:4317"}
+	for _, next := range []OTelConfig{
+		{Enabled: true, Exporter: ExporterOTLP, Endpoint: "localhost:4317"},
+		{Enabled: false},
+	} {
+		if err := InitWithConfig(nil, logsCfg); err != nil {
+			t.Fatalf("InitWithConfig returned error: %v", err)
+		}
+		exp := logExporter.(*mockLogExporter)
+		if err := InitWithConfig(nil, next); err != nil {
+			t.Fatalf("InitWithConfig returned error: %v", err)
+		}
+		_ = logger.Info("after reinit")
+
+		exp.mu.Lock()
+		shutdown, records := exp.shutdown, exp.records
+		exp.mu.Unlock()
+		if !shutdown {
+			t.Fatalf("enabled=%v: previous log exporter not shut down", next.Enabled)
+		}
+		for _, r := range records {
+			if r.Body().AsString() == "after reinit" {
+				t.Fatalf("enabled=%v: log forwarded to previous provider", next.Enabled)
+			}
+		}
+		if loggerProvider != nil {
+			t.Fatalf("enabled=%v: expected no LoggerProvider", next.Enabled)
+		}
+	}
+}
+
+// TestShutdownFlushesLogsWhenTracingFails ensures a failing TracerProvider
+// shutdown does not stop the LoggerProvider from being shut down.
+func TestShutdownFlushesLogsWhenTracingFails(t *testing.T) {
+	if err := logger.Init(); err != nil {
+		t.Fatalf("logger init: %v", err)
+	}
+	cfg := OTelConfig{Enabled: true, LogsEnabled: true, Exporter: ExporterOTLP, Endpoint: "localhost:4317"}
+	if err := InitWithConfig(nil, cfg); err != nil {
+		t.Fatalf("InitWithConfig returned error: %v", err)
+	}
+	exp := logExporter.(*mockLogExporter)
+
+	t.Setenv("OTEL_TEST_SHUTDOWN_TIMEOUT", "true")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := Shutdown(ctx)
+	if err == nil || !strings.Contains(err.Error(), "shutdown timeout") {
+		t.Fatalf("expected tracer shutdown error, got %v", err)
+	}
+	exp.mu.Lock()
+	shutdown := exp.shutdown
+	exp.mu.Unlock()
+	if !shutdown {
+		t.Fatal("log exporter not shut down")
+	}
+	if loggerProvider != nil {
+		t.Fatal("expected LoggerProvider to be reset")
+	}
+
+	t.Setenv("OTEL_TEST_SHUTDOWN_TIMEOUT", "")
+	if err := Shutdown(context.Background()); err != nil {
+		t.Fatalf("retried Shutdown returned error: %v", err)
+	}
+}
+
+// TestStartSpanWithOptions verifies span kind and attributes are applied.
+func TestStartSpanWithOptions(t *testing.T) {
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{"otel_enabled": true}))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	defer Shutdown(context.Background())
+
+	_, span := StartSpanWithOptions(context.Background(), "test", "publish",
+		WithSpanKind(oteltrace.SpanKindProducer),
+		WithAttributes(attribute.String("messaging.system", "kafka")),
+	)
+	span.End()
+
+	ro := span.(sdktrace.ReadOnlySpan)
+	if ro.SpanKind() != oteltrace.SpanKindProducer {
+		t.Fatalf("expected producer span, got %v", ro.SpanKind())
+	}
+	if len(ro.Attributes()) != 1 || ro.Attributes()[0].Value.AsString() != "kafka" {
+		t.Fatalf("unexpected attributes %v", ro.Attributes())
+	}
+}
+
+// TestSpanFromContext ensures the helpers see the span started on ctx.
+func TestSpanFromContext(t *testing.T) {
+	if IsRecording(context.Background()) {
+		t.Fatal("expected no recording span on an empty context")
+	}
+	if SpanFromContext(context.Background()).SpanContext().IsValid() {
+		t.Fatal("expected invalid span context on an empty context")
+	}
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{"otel_enabled": true}))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	defer Shutdown(context.Background())
+
+	ctx, span := StartSpan(context.Background(), "test", "operation")
+	defer span.End()
+
+	got := SpanFromContext(ctx)
+	if !got.SpanContext().IsValid() {
+		t.Fatal("expected valid span context")
+	}
+	if got.SpanContext().SpanID() != span.SpanContext().SpanID() {
+		t.Fatalf("expected span %s, got %s", span.SpanContext().SpanID(), got.SpanContext().SpanID())
+	}
+	if !IsRecording(ctx) {
+		t.Fatal("expected span to be recording")
+	}
+}
+
+// TestInjectExtractHeaders round-trips a span context through headers.
+func TestInjectExtractHeaders(t *testing.T) {
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{"otel_enabled": true}))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	defer Shutdown(context.Background())
+
+	ctx, span := StartSpan(context.Background(), "test", "producer")
+	defer span.End()
+
+	headers := InjectHeaders(ctx)
+	if headers["traceparent"] == "" {
+		t.Fatalf("expected traceparent header, got %v", headers)
+	}
+
+	got := SpanFromContext(ExtractContext(context.Background(), headers)).SpanContext()
+	want := span.SpanContext()
+	if got.TraceID() != want.TraceID() || got.SpanID() != want.SpanID() {
+		t.Fatalf("expected span context %v, got %v", want, got)
+	}
+	if !got.IsRemote() {
+		t.Fatal("expected extracted span context to be remote")
+	}
+
+	if SpanFromContext(ExtractContext(context.Background(), nil)).SpanContext().IsValid() {
+		t.Fatal("expected no span context from empty headers")
+	}
+}
+
+// TestStartSpanWithLinks ensures every upstream context becomes a link.
+func TestStartSpanWithLinks(t *testing.T) {
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{"otel_enabled": true}))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	defer Shutdown(context.Background())
+	recorder := tracetest.NewSpanRecorder()
+	GetTracerProvider().RegisterSpanProcessor(recorder)
+
+	first, span1 := StartSpan(context.Background(), "test", "message-1")
+	span1.End()
+	second, span2 := StartSpan(context.Background(), "test", "message-2")
+	span2.End()
+	// Upstream contexts arrive as headers, e.g. on consumed messages
+	links := []context.Context{
+		ExtractContext(context.Background(), InjectHeaders(first)),
+		ExtractContext(context.Background(), InjectHeaders(second)),
+		context.Background(),
+	}
+
+	_, batch := StartSpanWithLinks(context.Background(), "test", "process-batch", links)
+	batch.End()
+
+	spans := recorder.Ended()
+	got := spans[len(spans)-1]
+	if got.Name() != "process-batch" {
+		t.Fatalf("expected process-batch span, got %s", got.Name())
+	}
+	if len(got.Links()) != 2 {
+		t.Fatalf("expected 2 links, got %d", len(got.Links()))
+	}
+	for i, want := range []oteltrace.SpanContext{span1.SpanContext(), span2.SpanContext()} {
+		link := got.Links()[i].SpanContext
+		if link.TraceID() != want.TraceID() || link.SpanID() != want.SpanID() {
+			t.Fatalf("link %d: expected %v, got %v", i, want, link)
+		}
+	}
+}
+
+// TestWithSpan ensures errors returned by fn are recorded on the span.
+func TestWithSpan(t *testing.T) {
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{"otel_enabled": true}))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	defer Shutdown(context.Background())
+	recorder := tracetest.NewSpanRecorder()
+	GetTracerProvider().RegisterSpanProcessor(recorder)
+
+	if err := WithSpan(context.Background(), "test", "ok", func(ctx context.Context) error {
+		if !IsRecording(ctx) {
+			t.Fatal("expected fn to run inside a recording span")
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	boom := errors.New("boom")
+	if err := WithSpan(context.Background(), "test", "failing", func(ctx context.Context) error {
+		return boom
+	}); !errors.Is(err, boom) {
+		t.Fatalf("expected boom, got %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 ended spans, got %d", len(spans))
+	}
+	if spans[0].Status().Code != codes.Unset {
+		t.Fatalf("expected unset status, got %v", spans[0].Status())
+	}
+	failed := spans[1]
+	if failed.Name() != "failing" || failed.Status().Code != codes.Error || failed.Status().Description != "boom" {
+		t.Fatalf("unexpected span %s status %v", failed.Name(), failed.Status())
+	}
+	if len(failed.Events()) != 1 || failed.Events()[0].Name != "exception" {
+		t.Fatalf("expected recorded exception event, got %v", failed.Events())
+	}
+}
+
+// TestStdoutExporter ensures spans are printed when otel_exporter is stdout.
+func TestStdoutExporter(t *testing.T) {
+	if err := logger.Init(); err != nil {
+		t.Fatalf("logger init: %v", err)
+	}
+	var buf bytes.Buffer
+	origWriter := stdoutWriter
+	stdoutWriter = &buf
+	defer func() { stdoutWriter = origWriter }()
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"otel_enabled":  true,
+		"otel_exporter": "stdout",
+		"otel_endpoint": "localhost:4317",
+	}))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	defer Shutdown(context.Background())
+
+	_, span := StartSpan(context.Background(), "test", "stdout-span")
+	span.End()
+
+	if !strings.Contains(buf.String(), `"Name": "stdout-span"`) {
+		t.Fatalf("expected span on stdout, got %q", buf.String())
+	}
+}
+
+// TestUnsupportedExporter ensures unknown exporter types are rejected.
+func TestUnsupportedExporter(t *testing.T) {
+	err := InitWithConfig(nil, OTelConfig{Enabled: true, Exporter: "zipkin"})
+	if err == nil || !strings.Contains(err.Error(), "unsupported exporter") {
+		t.Fatalf("expected unsupported exporter error, got %v", err)
+	}
+}
+
+// TestOTLPHTTPEndpointAndHeaders ensures the HTTP exporter sends spans to the
+// configured endpoint with the configured headers.
+func TestOTLPHTTPEndpointAndHeaders(t *testing.T) {
+	t.Setenv("OTEL_TEST_MOCK_EXPORTER", "false")
+	if err := logger.Init(); err != nil {
+		t.Fatalf("logger init: %v", err)
+	}
+
+	type request struct {
+		path   string
+		apiKey string
+	}
+	received := make(chan request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case received <- request{path: r.URL.Path, apiKey: r.Header.Get("api-key")}:
+		default:
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{
+		"otel_enabled":  true,
+		"otel_insecure": true,
+		"otel_endpoint": strings.TrimPrefix(srv.URL, "http://"),
+		"otel_protocol": "http",
+		"otel_headers":  map[string]interface{}{"api-key": "secret"},
+	}))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	defer Shutdown(context.Background())
+
+	_, span := StartSpan(context.Background(), "test", "http-span")
+	span.End()
+
+	select {
+	case req := <-received:
+		if req.path != "/v1/traces" {
+			t.Fatalf("unexpected path %q", req.path)
+		}
+		if req.apiKey != "secret" {
+			t.Fatalf("expected api-key header, got %q", req.apiKey)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no spans exported to the configured endpoint")
+	}
+}
+
+// TestUnsupportedProtocol ensures unknown protocols are rejected.
+func TestUnsupportedProtocol(t *testing.T) {
+	err := InitWithConfig(nil, OTelConfig{Enabled: true, Endpoint: "localhost:4317", Protocol: "thrift"})
+	if err == nil || !strings.Contains(err.Error(), "unsupported protocol") {
+		t.Fatalf("expected unsupported protocol error, got %v", err)
+	}
+}
+
+// TestTracerForCaller ensures the tracer is named after the calling package.
+func TestTracerForCaller(t *testing.T) {
+	cfg, err := config.New(config.WithDefault(map[string]interface{}{"otel_enabled": true}))
+	if err != nil {
+		t.Fatalf("new config: %v", err)
+	}
+	if err := Init(cfg); err != nil {
+		t.Fatalf("Init returned error: %v", err)
+	}
+	defer Shutdown(context.Background())
+	recorder := tracetest.NewSpanRecorder()
+	GetTracerProvider().RegisterSpanProcessor(recorder)
+
+	_, span := TracerForCaller("fallback").Start(context.Background(), "op")
+	span.End()
+	// Closures report the package of the enclosing function too.
+	func() {
+		_, span := TracerForCaller("fallback").Start(context.Background(), "nested")
+		span.End()
+	}()
+
+	const want = "github.com/T-Prohmpossadhorn/go-core/otel"
+	for _, s := range recorder.Ended() {
+		if got := s.InstrumentationScope().Name; got != want {
+			t.Fatalf("span %s: expected tracer %q, got %q", s.Name(), want, got)
+		}
+	}
+	if len(recorder.Ended()) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(recorder.Ended()))
+	}
+	if got := callerPackage(100); got != "" {
+		t.Fatalf("expected no package for missing frame, got %q", got)
+	}
+}
+
+// TestFuncPackage verifies package paths are parsed from runtime function
+// names, including escaped dots and generics.
+func TestFuncPackage(t *testing.T) {
+	tests := map[string]string{
+		"github.com/acme/app/orders.(*Service).Get": "github.com/acme/app/orders",
+		"github.com/acme/app/orders.Get.func1":      "github.com/acme/app/orders",
+		"gopkg.in/yaml%2ev3.Marshal":                "gopkg.in/yaml.v3",
+		"gopkg.in/yaml%2ev3.(*decoder).unmarshal":   "gopkg.in/yaml.v3",
+		"example.com/foo/bar%2ev2.(*T).M":           "example.com/foo/bar.v2",
+		"example.com/foo.v2/sub.New[...]":           "example.com/foo.v2/sub",
+		"main.main":                                 "main",
+		"main":                                      "",
+	}
+	for name, want := range tests {
+		if got := funcPackage(name); got != want {
+			t.Errorf("funcPackage(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
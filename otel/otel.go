@@ -2,33 +2,71 @@ package otel
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/url"
 	"os"
+	"runtime"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/T-Prohmpossadhorn/go-core/config"
 	"github.com/T-Prohmpossadhorn/go-core/logger"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploggrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlplog/otlploghttp"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdoutlog"
 	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/propagation"
+	sdklog "go.opentelemetry.io/otel/sdk/log"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	oteltrace "go.opentelemetry.io/otel/trace"
 	"go.opentelemetry.io/otel/trace/noop"
 )
 
 type OTelConfig struct {
-	Endpoint string `mapstructure:"otel_endpoint" default:"localhost:4317"`
-	Insecure bool   `mapstructure:"otel_insecure" default:"true"`
-	Enabled  bool   `mapstructure:"otel_enabled" default:"false"`
+	Endpoint    string            `mapstructure:"otel_endpoint" default:"localhost:4317"`
+	Insecure    bool              `mapstructure:"otel_insecure" default:"true"`
+	Enabled     bool              `mapstructure:"otel_enabled" default:"false"`
+	LogsEnabled bool              `mapstructure:"otel_logs_enabled" default:"false"`
+	Exporter    string            `mapstructure:"otel_exporter" default:"otlp"`
+	Protocol    string            `mapstructure:"otel_protocol" default:"grpc"`
+	Headers     map[string]string `mapstructure:"otel_headers"`
+}
+
+// Supported values for OTelConfig.Exporter.
+const (
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// Supported values for OTelConfig.Protocol.
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// useStdout reports whether spans and logs should be printed to stdout
+// instead of being sent to an OTLP endpoint.
+func (c OTelConfig) useStdout() bool {
+	return c.Exporter == ExporterStdout || c.Endpoint == ""
 }
 
 var (
 	tracerProvider *sdktrace.TracerProvider
+	loggerProvider *sdklog.LoggerProvider
+	logExporter    sdklog.Exporter
 	otelMu         sync.RWMutex
+	// stdoutWriter is where the stdout exporters write; replaced in tests
+	stdoutWriter io.Writer = os.Stdout
 )
 
 // mockExporter is a no-op exporter for testing to avoid network calls
@@ -42,6 +80,33 @@ func (m *mockExporter) Shutdown(ctx context.Context) error {
 	return nil
 }
 
+// mockLogExporter records exported log records for testing
+type mockLogExporter struct {
+	mu       sync.Mutex
+	records  []sdklog.Record
+	shutdown bool
+}
+
+func (m *mockLogExporter) Export(ctx context.Context, records []sdklog.Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range records {
+		m.records = append(m.records, r.Clone())
+	}
+	return nil
+}
+
+func (m *mockLogExporter) Shutdown(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.shutdown = true
+	return nil
+}
+
+func (m *mockLogExporter) ForceFlush(ctx context.Context) error {
+	return nil
+}
+
 // validateEndpoint checks if the endpoint is valid by ensuring it has a host and port.
 func validateEndpoint(endpoint string) error {
 	if endpoint == "" {
@@ -74,9 +139,13 @@ func Init(c *config.Config) error {
 	_ = logger.SetLevel(level)
 
 	cfg := OTelConfig{
-		Endpoint: c.GetStringWithDefault("otel_endpoint", "localhost:4317"),
-		Insecure: c.GetBool("otel_insecure"),
-		Enabled:  c.GetBool("otel_enabled"),
+		Endpoint:    c.GetStringWithDefault("otel_endpoint", "localhost:4317"),
+		Insecure:    c.GetBool("otel_insecure"),
+		Enabled:     c.GetBool("otel_enabled"),
+		LogsEnabled: c.GetBool("otel_logs_enabled"),
+		Exporter:    c.GetStringWithDefault("otel_exporter", ExporterOTLP),
+		Protocol:    c.GetStringWithDefault("otel_protocol", ProtocolGRPC),
+		Headers:     c.GetStringMapString("otel_headers"),
 	}
 	return InitWithConfig(c, cfg)
 }
@@ -90,18 +159,31 @@ func InitWithConfig(c *config.Config, cfg OTelConfig) error {
 
 	if !cfg.Enabled {
 		logger.Info("OpenTelemetry disabled via config")
-		tracerProvider = nil
+		shutdownPrevious(ctx)
 		return nil
 	}
 
+	if cfg.Exporter != "" && cfg.Exporter != ExporterOTLP && cfg.Exporter != ExporterStdout {
+		err := fmt.Errorf("unsupported exporter: %s", cfg.Exporter)
+		logger.Error("Invalid exporter", logger.ErrField(err))
+		return err
+	}
+	if cfg.Protocol != "" && cfg.Protocol != ProtocolGRPC && cfg.Protocol != ProtocolHTTP {
+		err := fmt.Errorf("unsupported protocol: %s", cfg.Protocol)
+		logger.Error("Invalid protocol", logger.ErrField(err))
+		return err
+	}
+
 	// Validate endpoint
-	if err := validateEndpoint(cfg.Endpoint); err != nil {
-		logger.Error("Invalid endpoint", logger.ErrField(err))
-		return fmt.Errorf("failed to validate endpoint: %w", err)
+	if !cfg.useStdout() {
+		if err := validateEndpoint(cfg.Endpoint); err != nil {
+			logger.Error("Invalid endpoint", logger.ErrField(err))
+			return fmt.Errorf("failed to validate endpoint: %w", err)
+		}
 	}
 
 	var exporter sdktrace.SpanExporter
-	if cfg.Endpoint == "" {
+	if cfg.useStdout() {
 		// Simulate stdouttrace failure for testing
 		if os.Getenv("OTEL_TEST_STDOUT_FAIL") == "true" {
 			err := fmt.Errorf("simulated stdouttrace failure")
@@ -109,7 +191,7 @@ func InitWithConfig(c *config.Config, cfg OTelConfig) error {
 			return fmt.Errorf("failed to create stdouttrace exporter: %w", err)
 		}
 		// Use stdouttrace exporter
-		exp, err := stdouttrace.New(stdouttrace.WithPrettyPrint())
+		exp, err := stdouttrace.New(stdouttrace.WithWriter(stdoutWriter), stdouttrace.WithPrettyPrint())
 		if err != nil {
 			logger.Error("Failed to create stdouttrace exporter", logger.ErrField(err))
 			return fmt.Errorf("failed to create stdouttrace exporter: %w", err)
@@ -127,13 +209,7 @@ func InitWithConfig(c *config.Config, cfg OTelConfig) error {
 				logger.Error("Failed to create OTLP exporter", logger.ErrField(err))
 				return fmt.Errorf("failed to create OTLP exporter: %w", err)
 			}
-			opts := []otlptracegrpc.Option{
-				otlptracegrpc.WithEndpoint(cfg.Endpoint),
-			}
-			if cfg.Insecure {
-				opts = append(opts, otlptracegrpc.WithInsecure())
-			}
-			exp, err := otlptracegrpc.New(ctx, opts...)
+			exp, err := newOTLPTraceExporter(ctx, cfg)
 			if err != nil {
 				logger.Error("Failed to create OTLP exporter", logger.ErrField(err))
 				return fmt.Errorf("failed to create OTLP exporter: %w", err)
@@ -142,6 +218,7 @@ func InitWithConfig(c *config.Config, cfg OTelConfig) error {
 		}
 	}
 
+	shutdownPrevious(ctx)
 	tracerProvider = sdktrace.NewTracerProvider(
 		sdktrace.WithSpanProcessor(sdktrace.NewSimpleSpanProcessor(exporter)),
 		sdktrace.WithSampler(sdktrace.AlwaysSample()),
@@ -149,11 +226,136 @@ func InitWithConfig(c *config.Config, cfg OTelConfig) error {
 	otel.SetTracerProvider(tracerProvider)
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
 
+	if cfg.LogsEnabled {
+		if err := initLogs(ctx, cfg); err != nil {
+			return err
+		}
+	}
+
 	logger.Debug("TracerProvider initialized", logger.Any("tracerProvider", tracerProvider))
 	logger.Info("OpenTelemetry initialized successfully")
 	return nil
 }
 
+// shutdownPrevious shuts down the providers of an earlier InitWithConfig, if
+// any, and detaches the LoggerProvider from the logger. Failures are logged
+// but do not stop the new configuration from taking effect.
+func shutdownPrevious(ctx context.Context) {
+	if err := errors.Join(shutdownTracing(ctx), shutdownLogs(ctx)); err != nil {
+		logger.Warn("Failed to shutdown previous OpenTelemetry providers", logger.ErrField(err))
+	}
+	tracerProvider = nil
+}
+
+// shutdownTracing shuts down the TracerProvider, if any, and resets it. On
+// failure the provider is kept so that Shutdown can be retried.
+func shutdownTracing(ctx context.Context) error {
+	if tracerProvider == nil {
+		return nil
+	}
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		logger.Error("Failed to shutdown TracerProvider", logger.ErrField(err))
+		return fmt.Errorf("failed to shutdown TracerProvider: %w", err)
+	}
+	tracerProvider = nil
+	return nil
+}
+
+// shutdownLogs detaches the LoggerProvider, if any, from the logger, shuts
+// it down and resets it.
+func shutdownLogs(ctx context.Context) error {
+	if loggerProvider == nil {
+		return nil
+	}
+	logger.SetOTelLoggerProvider(nil)
+	err := loggerProvider.Shutdown(ctx)
+	loggerProvider = nil
+	logExporter = nil
+	if err != nil {
+		logger.Error("Failed to shutdown LoggerProvider", logger.ErrField(err))
+		return fmt.Errorf("failed to shutdown LoggerProvider: %w", err)
+	}
+	return nil
+}
+
+// initLogs creates a LoggerProvider using the same endpoint selection as the
+// trace exporter and forwards logger output to it.
+func initLogs(ctx context.Context, cfg OTelConfig) error {
+	var exporter sdklog.Exporter
+	switch {
+	case cfg.useStdout():
+		exp, err := stdoutlog.New(stdoutlog.WithWriter(stdoutWriter), stdoutlog.WithPrettyPrint())
+		if err != nil {
+			logger.Error("Failed to create stdoutlog exporter", logger.ErrField(err))
+			return fmt.Errorf("failed to create stdoutlog exporter: %w", err)
+		}
+		exporter = exp
+	case os.Getenv("OTEL_TEST_MOCK_EXPORTER") == "true":
+		exporter = &mockLogExporter{}
+	default:
+		exp, err := newOTLPLogExporter(ctx, cfg)
+		if err != nil {
+			logger.Error("Failed to create OTLP log exporter", logger.ErrField(err))
+			return fmt.Errorf("failed to create OTLP log exporter: %w", err)
+		}
+		exporter = exp
+	}
+
+	logExporter = exporter
+	loggerProvider = sdklog.NewLoggerProvider(
+		sdklog.WithProcessor(sdklog.NewSimpleProcessor(exporter)),
+	)
+	logger.SetOTelLoggerProvider(loggerProvider)
+	logger.Info("OpenTelemetry logs enabled")
+	return nil
+}
+
+// newOTLPTraceExporter creates an OTLP span exporter for cfg.Endpoint over
+// gRPC or HTTP, sending cfg.Headers with every export.
+func newOTLPTraceExporter(ctx context.Context, cfg OTelConfig) (sdktrace.SpanExporter, error) {
+	if cfg.Protocol == ProtocolHTTP {
+		opts := []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(cfg.Endpoint),
+			otlptracehttp.WithHeaders(cfg.Headers),
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		return otlptracehttp.New(ctx, opts...)
+	}
+	opts := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(cfg.Endpoint),
+		otlptracegrpc.WithHeaders(cfg.Headers),
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlptracegrpc.WithInsecure())
+	}
+	return otlptracegrpc.New(ctx, opts...)
+}
+
+// newOTLPLogExporter creates an OTLP log exporter with the same endpoint,
+// protocol and headers as the span exporter.
+func newOTLPLogExporter(ctx context.Context, cfg OTelConfig) (sdklog.Exporter, error) {
+	if cfg.Protocol == ProtocolHTTP {
+		opts := []otlploghttp.Option{
+			otlploghttp.WithEndpoint(cfg.Endpoint),
+			otlploghttp.WithHeaders(cfg.Headers),
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlploghttp.WithInsecure())
+		}
+		return otlploghttp.New(ctx, opts...)
+	}
+	opts := []otlploggrpc.Option{
+		otlploggrpc.WithEndpoint(cfg.Endpoint),
+		otlploggrpc.WithHeaders(cfg.Headers),
+	}
+	if cfg.Insecure {
+		opts = append(opts, otlploggrpc.WithInsecure())
+	}
+	return otlploggrpc.New(ctx, opts...)
+}
+
 func Shutdown(ctx context.Context) error {
 	otelMu.Lock()
 	defer otelMu.Unlock()
@@ -161,26 +363,25 @@ func Shutdown(ctx context.Context) error {
 		return fmt.Errorf("tracer provider not initialized")
 	}
 	logger.Info("Shutting down OpenTelemetry")
+	// Both providers are shut down even if one fails, so buffered logs are
+	// flushed when tracing fails to shut down.
+	var traceErr error
 	// Simulate timeout for testing
 	if os.Getenv("OTEL_TEST_SHUTDOWN_TIMEOUT") == "true" {
 		select {
 		case <-time.After(10 * time.Millisecond): // Longer than test timeout
-			err := fmt.Errorf("shutdown timeout: context deadline exceeded")
-			logger.Error("Failed to shutdown TracerProvider", logger.ErrField(err))
-			return err
+			traceErr = fmt.Errorf("shutdown timeout: context deadline exceeded")
 		case <-ctx.Done():
-			err := fmt.Errorf("shutdown timeout: %w", ctx.Err())
-			logger.Error("Failed to shutdown TracerProvider", logger.ErrField(err))
-			return err
+			traceErr = fmt.Errorf("shutdown timeout: %w", ctx.Err())
 		}
+		logger.Error("Failed to shutdown TracerProvider", logger.ErrField(traceErr))
+	} else {
+		traceErr = shutdownTracing(ctx)
 	}
-	err := tracerProvider.Shutdown(ctx)
-	if err != nil {
-		logger.Error("Failed to shutdown TracerProvider", logger.ErrField(err))
-		return fmt.Errorf("failed to shutdown TracerProvider: %w", err)
+	if err := errors.Join(traceErr, shutdownLogs(ctx)); err != nil {
+		return err
 	}
 	logger.Info("OpenTelemetry shutdown successfully")
-	tracerProvider = nil // Reset to ensure subsequent Shutdown calls fail
 	return nil
 }
 
@@ -195,6 +396,58 @@ func GetTracer(name string) oteltrace.Tracer {
 	return tracerProvider.Tracer(name)
 }
 
+// TracerForCaller returns a tracer named after the import path of the
+// calling package, e.g. "github.com/acme/app/orders", so packages do not have
+// to repeat their name as a string. fallback is used when the caller cannot
+// be determined.
+func TracerForCaller(fallback string) oteltrace.Tracer {
+	name := callerPackage(2)
+	if name == "" {
+		name = fallback
+	}
+	return GetTracer(name)
+}
+
+// callerPackage returns the import path of the package of the function skip
+// frames above its caller, or "" if it is unknown.
+func callerPackage(skip int) string {
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return ""
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return ""
+	}
+	return funcPackage(fn.Name())
+}
+
+// funcPackage returns the import path of the package of the function with
+// the given runtime name, or "" if it has none. Names look like
+// "github.com/acme/app/orders.(*Service).Get". The compiler escapes dots in
+// the last path element, as in "gopkg.in/yaml%2ev3.Marshal", so the path
+// ends at the first dot after the last slash and is then unescaped.
+func funcPackage(name string) string {
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return ""
+	}
+	path, err := url.PathUnescape(name[:slash+1+dot])
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// GetTracerProvider returns the TracerProvider created by Init, or nil when
+// OpenTelemetry has not been initialized.
+func GetTracerProvider() *sdktrace.TracerProvider {
+	otelMu.RLock()
+	defer otelMu.RUnlock()
+	return tracerProvider
+}
+
 // StartSpan is a convenience function that retrieves a tracer by name and
 // starts a span in a single call. It falls back to a noop tracer when the
 // tracer provider has not been initialized.
@@ -202,3 +455,83 @@ func StartSpan(ctx context.Context, tracerName, spanName string) (context.Contex
 	tracer := GetTracer(tracerName)
 	return tracer.Start(ctx, spanName)
 }
+
+// SpanOption configures a span started by StartSpanWithOptions.
+type SpanOption = oteltrace.SpanStartOption
+
+// WithSpanKind sets the kind of the span, e.g. oteltrace.SpanKindProducer.
+func WithSpanKind(kind oteltrace.SpanKind) SpanOption {
+	return oteltrace.WithSpanKind(kind)
+}
+
+// WithAttributes sets initial attributes on the span.
+func WithAttributes(attrs ...attribute.KeyValue) SpanOption {
+	return oteltrace.WithAttributes(attrs...)
+}
+
+// WithLinks links the span to other span contexts.
+func WithLinks(links ...oteltrace.Link) SpanOption {
+	return oteltrace.WithLinks(links...)
+}
+
+// StartSpanWithOptions is like StartSpan but applies the given options, such
+// as span kind, attributes and links, to the new span.
+func StartSpanWithOptions(ctx context.Context, tracerName, spanName string, opts ...SpanOption) (context.Context, oteltrace.Span) {
+	tracer := GetTracer(tracerName)
+	return tracer.Start(ctx, spanName, opts...)
+}
+
+// StartSpanWithLinks starts a span that links to the span context of each of
+// links, such as the contexts extracted from a batch of consumed messages with
+// ExtractContext. Contexts without a valid span context are skipped. The new
+// span is a child of the span in ctx, if any.
+func StartSpanWithLinks(ctx context.Context, tracerName, spanName string, links []context.Context, opts ...SpanOption) (context.Context, oteltrace.Span) {
+	spanLinks := make([]oteltrace.Link, 0, len(links))
+	for _, linkCtx := range links {
+		sc := oteltrace.SpanContextFromContext(linkCtx)
+		if sc.IsValid() {
+			spanLinks = append(spanLinks, oteltrace.Link{SpanContext: sc})
+		}
+	}
+	return StartSpanWithOptions(ctx, tracerName, spanName, append(opts[:len(opts):len(opts)], WithLinks(spanLinks...))...)
+}
+
+// SpanFromContext returns the span stored in ctx, or a no-op span when there
+// is none. It saves callers an import of the trace API.
+func SpanFromContext(ctx context.Context) oteltrace.Span {
+	return oteltrace.SpanFromContext(ctx)
+}
+
+// IsRecording reports whether the span in ctx is recording events, which is
+// false when tracing is disabled or the span was not sampled.
+func IsRecording(ctx context.Context) bool {
+	return oteltrace.SpanFromContext(ctx).IsRecording()
+}
+
+// WithSpan starts a span named spanName, calls fn with the span's context and
+// ends the span when fn returns. An error returned by fn is recorded on the
+// span, sets its status to Error and is returned unchanged.
+func WithSpan(ctx context.Context, tracerName, spanName string, fn func(ctx context.Context) error) error {
+	ctx, span := StartSpan(ctx, tracerName, spanName)
+	defer span.End()
+	if err := fn(ctx); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
+
+// InjectHeaders returns the trace context of ctx encoded as headers using the
+// global propagator, for sending over any transport such as message headers.
+func InjectHeaders(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// ExtractContext returns a copy of ctx carrying the trace context decoded from
+// headers produced by InjectHeaders.
+func ExtractContext(ctx context.Context, headers map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(headers))
+}
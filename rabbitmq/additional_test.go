@@ -20,6 +20,17 @@ func (e *errChannel) PublishWithContext(context.Context, string, string, bool, b
 func (e *errChannel) ConsumeWithContext(context.Context, string, string, bool, bool, bool, bool, amqp.Table) (<-chan amqp.Delivery, error) {
 	return nil, errors.New("consume")
 }
+func (e *errChannel) Tx() error                                                { return nil }
+func (e *errChannel) TxCommit() error                                          { return nil }
+func (e *errChannel) TxRollback() error                                        { return nil }
+func (e *errChannel) QueueBind(string, string, string, bool, amqp.Table) error { return nil }
+func (e *errChannel) Confirm(bool) error                                       { return nil }
+func (e *errChannel) NotifyPublish(c chan amqp.Confirmation) chan amqp.Confirmation {
+	return c
+}
+func (e *errChannel) ExchangeDeclare(string, string, bool, bool, bool, bool, amqp.Table) error {
+	return nil
+}
 func (e *errChannel) Close() error { return nil }
 
 type errConnConsume struct{}
@@ -34,7 +45,7 @@ func TestPublishJSONMarshalError(t *testing.T) {
 	cfg, _ := config.New()
 	ch := &mockChannel{consumeCh: make(chan amqp.Delivery)}
 	origDial := dialFunc
-	dialFunc = func(string) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
 	defer func() { dialFunc = origDial }()
 	rmq, _ := New(cfg)
 	err := PublishJSON(context.Background(), rmq, "q", badStruct{})
@@ -46,7 +57,7 @@ func TestPublishJSONMarshalError(t *testing.T) {
 // TestConsumeWithChannelError ensures Consume handles ConsumeWithContext errors.
 func TestConsumeWithChannelError(t *testing.T) {
 	origDial := dialFunc
-	dialFunc = func(string) (amqpConn, error) { return &errConnConsume{}, nil }
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &errConnConsume{}, nil }
 	defer func() { dialFunc = origDial }()
 	cfg, _ := config.New()
 	rmq, _ := New(cfg)
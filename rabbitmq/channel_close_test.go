@@ -21,6 +21,17 @@ func (m *mockChan) PublishWithContext(ctx context.Context, exchange, key string,
 func (m *mockChan) ConsumeWithContext(ctx context.Context, queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
 	return nil, nil
 }
+func (m *mockChan) Tx() error                                                { return nil }
+func (m *mockChan) TxCommit() error                                          { return nil }
+func (m *mockChan) TxRollback() error                                        { return nil }
+func (m *mockChan) QueueBind(string, string, string, bool, amqp.Table) error { return nil }
+func (m *mockChan) Confirm(bool) error                                       { return nil }
+func (m *mockChan) NotifyPublish(c chan amqp.Confirmation) chan amqp.Confirmation {
+	return c
+}
+func (m *mockChan) ExchangeDeclare(string, string, bool, bool, bool, bool, amqp.Table) error {
+	return nil
+}
 func (m *mockChan) Close() error { return nil }
 
 type mockConnForChannel struct{}
@@ -30,7 +41,7 @@ func (m *mockConnForChannel) Close() error                  { return nil }
 
 func TestRabbitMQChannelClose(t *testing.T) {
 	origDial := dialFunc
-	dialFunc = func(string) (amqpConn, error) { return &mockConnForChannel{}, nil }
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConnForChannel{}, nil }
 	defer func() { dialFunc = origDial }()
 
 	cfg, _ := config.New()
@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"os"
 
 	"github.com/T-Prohmpossadhorn/go-core/config"
 	"github.com/T-Prohmpossadhorn/go-core/logger"
@@ -15,12 +14,10 @@ func main() {
 	defer logger.Sync()
 
 	cfg, _ := config.New(config.WithDefault(map[string]interface{}{
-		"otel_enabled": true,
+		"otel_enabled":  true,
+		"otel_exporter": "stdout", // print spans locally instead of sending them to a collector
 	}))
 
-	// Use mock exporter for demonstration
-	os.Setenv("OTEL_TEST_MOCK_EXPORTER", "true")
-	defer os.Unsetenv("OTEL_TEST_MOCK_EXPORTER")
 	otel.Init(cfg)
 	defer otel.Shutdown(context.Background())
 
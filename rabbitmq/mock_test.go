@@ -1,37 +1,109 @@
 package rabbitmq
 
 import (
+	"bytes"
 	"context"
+	"encoding/gob"
 	"encoding/json"
 	"fmt"
 	"os"
+	"sync"
 	"testing"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 
 	"github.com/T-Prohmpossadhorn/go-core/config"
 	"github.com/T-Prohmpossadhorn/go-core/otel"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
+type declareCall struct {
+	name       string
+	durable    bool
+	autoDelete bool
+	exclusive  bool
+	args       amqp.Table
+}
+
 type mockChannel struct {
+	// mu guards declared and closed, which abandoned declares update from
+	// their own goroutine
+	mu         sync.Mutex
+	declared   []declareCall
 	published  []amqp.Publishing
+	keys       []string
 	consumeCh  chan amqp.Delivery
 	closed     bool
 	declareErr error
-	consumeErr error
-	publishErr error
+	// declareDelay makes QueueDeclare block, like a slow broker
+	declareDelay time.Duration
+	consumeErr   error
+	publishErr   error
+	onPublish    func(amqp.Publishing)
+	// failPublishAt makes the n-th publish (1-based) fail with publishErr
+	failPublishAt int
+	publishes     int
+	txCalls       []string
+	binds         []bindCall
+	exchanges     []exchangeCall
+	bindErr       error
+	// confirms receives a confirmation per publish once Confirm was called;
+	// deliveryTag counts publishes since then and nackTags are nacked.
+	confirms    chan amqp.Confirmation
+	deliveryTag uint64
+	nackTags    map[uint64]bool
+}
+
+type bindCall struct {
+	queue, key, exchange string
+	args                 amqp.Table
+}
+
+type exchangeCall struct {
+	name, kind                    string
+	durable, autoDelete, internal bool
+	args                          amqp.Table
 }
 
 func (m *mockChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	time.Sleep(m.declareDelay)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.declared = append(m.declared, declareCall{name, durable, autoDelete, exclusive, args})
+	if name == "" {
+		name = fmt.Sprintf("amq.gen-%d", len(m.declared))
+	}
 	return amqp.Queue{Name: name}, m.declareErr
 }
 
+func (m *mockChannel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	m.binds = append(m.binds, bindCall{name, key, exchange, args})
+	return m.bindErr
+}
+
+func (m *mockChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	m.exchanges = append(m.exchanges, exchangeCall{name, kind, durable, autoDelete, internal, args})
+	return nil
+}
+
 func (m *mockChannel) PublishWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
-	if m.publishErr != nil {
+	m.publishes++
+	if m.publishErr != nil && (m.failPublishAt == 0 || m.failPublishAt == m.publishes) {
 		return m.publishErr
 	}
 	m.published = append(m.published, msg)
+	m.keys = append(m.keys, key)
+	if m.confirms != nil {
+		m.deliveryTag++
+		m.confirms <- amqp.Confirmation{DeliveryTag: m.deliveryTag, Ack: !m.nackTags[m.deliveryTag]}
+	}
+	if m.onPublish != nil {
+		m.onPublish(msg)
+	}
 	return nil
 }
 
@@ -42,7 +114,29 @@ func (m *mockChannel) ConsumeWithContext(ctx context.Context, queue, consumer st
 	return m.consumeCh, nil
 }
 
-func (m *mockChannel) Close() error { m.closed = true; return nil }
+func (m *mockChannel) Tx() error         { m.txCalls = append(m.txCalls, "tx"); return nil }
+func (m *mockChannel) TxCommit() error   { m.txCalls = append(m.txCalls, "commit"); return nil }
+func (m *mockChannel) TxRollback() error { m.txCalls = append(m.txCalls, "rollback"); return nil }
+
+func (m *mockChannel) NotifyPublish(c chan amqp.Confirmation) chan amqp.Confirmation {
+	m.confirms = c
+	return c
+}
+
+func (m *mockChannel) Confirm(bool) error { m.deliveryTag = 0; return nil }
+
+func (m *mockChannel) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+func (m *mockChannel) isClosed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.closed
+}
 
 type mockConn struct {
 	ch     *mockChannel
@@ -63,7 +157,7 @@ func TestRabbitMQPublishConsumeMock(t *testing.T) {
 	close(ch.consumeCh)
 
 	origDial := dialFunc
-	dialFunc = func(string) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
 	defer func() { dialFunc = origDial }()
 
 	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
@@ -91,7 +185,7 @@ func TestRabbitMQPublishConsumeJSONMock(t *testing.T) {
 	close(ch.consumeCh)
 
 	origDial := dialFunc
-	dialFunc = func(string) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
 	defer func() { dialFunc = origDial }()
 
 	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
@@ -115,7 +209,7 @@ func TestRabbitMQCloseMock(t *testing.T) {
 	ch := &mockChannel{consumeCh: make(chan amqp.Delivery)}
 	conn := &mockConn{ch: ch}
 	origDial := dialFunc
-	dialFunc = func(string) (amqpConn, error) { return conn, nil }
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return conn, nil }
 	defer func() { dialFunc = origDial }()
 
 	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
@@ -134,7 +228,7 @@ func TestRabbitMQCloseMock(t *testing.T) {
 func TestRabbitMQPublishCanceledMock(t *testing.T) {
 	ch := &mockChannel{consumeCh: make(chan amqp.Delivery)}
 	origDial := dialFunc
-	dialFunc = func(string) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
 	defer func() { dialFunc = origDial }()
 
 	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
@@ -150,7 +244,7 @@ func TestRabbitMQPublishCanceledMock(t *testing.T) {
 func TestRabbitMQPublishTracingMock(t *testing.T) {
 	ch := &mockChannel{consumeCh: make(chan amqp.Delivery)}
 	origDial := dialFunc
-	dialFunc = func(string) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
 	defer func() { dialFunc = origDial }()
 
 	logWriter, _, cleanup := setupLogger(t)
@@ -180,7 +274,7 @@ func TestRabbitMQPublishTracingMock(t *testing.T) {
 func TestRabbitMQConsumeErrorMock(t *testing.T) {
 	ch := &mockChannel{declareErr: fmt.Errorf("boom")}
 	origDial := dialFunc
-	dialFunc = func(string) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
 	defer func() { dialFunc = origDial }()
 
 	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
@@ -194,7 +288,7 @@ func TestRabbitMQConsumeErrorMock(t *testing.T) {
 func TestRabbitMQPublishErrorMock(t *testing.T) {
 	ch := &mockChannel{publishErr: fmt.Errorf("boom")}
 	origDial := dialFunc
-	dialFunc = func(string) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
 	defer func() { dialFunc = origDial }()
 
 	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
@@ -207,7 +301,7 @@ func TestRabbitMQPublishErrorMock(t *testing.T) {
 
 func TestRabbitMQNewDialError(t *testing.T) {
 	origDial := dialFunc
-	dialFunc = func(string) (amqpConn, error) { return nil, fmt.Errorf("dial") }
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return nil, fmt.Errorf("dial") }
 	defer func() { dialFunc = origDial }()
 
 	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
@@ -217,7 +311,7 @@ func TestRabbitMQNewDialError(t *testing.T) {
 
 func TestRabbitMQNewChannelError(t *testing.T) {
 	origDial := dialFunc
-	dialFunc = func(string) (amqpConn, error) { return &errConn{}, nil }
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &errConn{}, nil }
 	defer func() { dialFunc = origDial }()
 
 	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
@@ -228,7 +322,7 @@ func TestRabbitMQNewChannelError(t *testing.T) {
 func TestRabbitMQPublishDeclareErrorMock(t *testing.T) {
 	ch := &mockChannel{declareErr: fmt.Errorf("decl")}
 	origDial := dialFunc
-	dialFunc = func(string) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
 	defer func() { dialFunc = origDial }()
 
 	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
@@ -242,7 +336,7 @@ func TestRabbitMQPublishDeclareErrorMock(t *testing.T) {
 func TestRabbitMQPublishInjectsTraceContext(t *testing.T) {
 	ch := &mockChannel{consumeCh: make(chan amqp.Delivery)}
 	origDial := dialFunc
-	dialFunc = func(string) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
 	defer func() { dialFunc = origDial }()
 
 	cfg, _ := config.New(config.WithDefault(map[string]interface{}{
@@ -271,7 +365,7 @@ func TestRabbitMQConsumeJSONInvalidDataMock(t *testing.T) {
 	close(ch.consumeCh)
 
 	origDial := dialFunc
-	dialFunc = func(string) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
 	defer func() { dialFunc = origDial }()
 
 	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
@@ -285,3 +379,798 @@ func TestRabbitMQConsumeJSONInvalidDataMock(t *testing.T) {
 	_, ok := <-out
 	require.False(t, ok)
 }
+
+func TestRabbitMQDeclareQueueOptionsMock(t *testing.T) {
+	ch := &mockChannel{consumeCh: make(chan amqp.Delivery)}
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	rmq, err := New(cfg)
+	require.NoError(t, err)
+
+	opts := QueueOptions{AutoDelete: true, Exclusive: true, Args: amqp.Table{"x-expires": int32(60000)}}
+	name, err := rmq.DeclareQueue("replies", opts)
+	require.NoError(t, err)
+	require.Equal(t, "replies", name)
+
+	_, err = rmq.Consume(context.Background(), "replies")
+	require.NoError(t, err)
+	require.NoError(t, rmq.Publish(context.Background(), "tasks", []byte("x")))
+
+	require.Len(t, ch.declared, 3)
+	for _, d := range ch.declared[:2] {
+		require.Equal(t, declareCall{"replies", false, true, true, opts.Args}, d)
+	}
+	require.Equal(t, declareCall{name: "tasks", durable: true}, ch.declared[2])
+}
+
+func TestRabbitMQCallMock(t *testing.T) {
+	ch := &mockChannel{consumeCh: make(chan amqp.Delivery, 2)}
+	ch.onPublish = func(p amqp.Publishing) {
+		ch.consumeCh <- amqp.Delivery{CorrelationId: "other", Body: []byte("wrong")}
+		ch.consumeCh <- amqp.Delivery{CorrelationId: p.CorrelationId, Body: []byte("pong")}
+	}
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	rmq, err := New(cfg)
+	require.NoError(t, err)
+
+	reply, err := rmq.Call(context.Background(), "rpc", []byte("ping"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("pong"), reply)
+
+	require.Len(t, ch.published, 1)
+	require.NotEmpty(t, ch.published[0].CorrelationId)
+	require.Equal(t, "amq.gen-1", ch.published[0].ReplyTo)
+	require.Equal(t, declareCall{name: "", autoDelete: true, exclusive: true}, ch.declared[0])
+}
+
+func TestRabbitMQCallTimeoutMock(t *testing.T) {
+	ch := &mockChannel{consumeCh: make(chan amqp.Delivery)}
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	rmq, err := New(cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = rmq.Call(ctx, "rpc", []byte("ping"))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRabbitMQSpanKindsMock(t *testing.T) {
+	ch := &mockChannel{consumeCh: make(chan amqp.Delivery, 1)}
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{
+		"otel_enabled": true,
+	}))
+	os.Setenv("OTEL_TEST_MOCK_EXPORTER", "true")
+	defer os.Unsetenv("OTEL_TEST_MOCK_EXPORTER")
+	require.NoError(t, otel.Init(cfg))
+	defer otel.Shutdown(context.Background())
+	recorder := tracetest.NewSpanRecorder()
+	otel.GetTracerProvider().RegisterSpanProcessor(recorder)
+
+	rmq, err := New(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, rmq.Publish(context.Background(), "q", []byte("x")))
+	ch.consumeCh <- amqp.Delivery{Headers: ch.published[0].Headers, Body: ch.published[0].Body}
+	close(ch.consumeCh)
+	out, err := rmq.Consume(context.Background(), "q")
+	require.NoError(t, err)
+	<-out
+
+	kinds := map[string]oteltrace.SpanKind{}
+	for _, s := range recorder.Ended() {
+		kinds[s.Name()] = s.SpanKind()
+	}
+	require.Equal(t, oteltrace.SpanKindProducer, kinds["Publish"])
+	require.Equal(t, oteltrace.SpanKindConsumer, kinds["ConsumeMessage"])
+}
+
+// poolConn hands out a fresh mockChannel for every Channel call.
+type poolConn struct{ chans []*mockChannel }
+
+func (p *poolConn) Channel() (amqpChannel, error) {
+	ch := &mockChannel{consumeCh: make(chan amqp.Delivery)}
+	p.chans = append(p.chans, ch)
+	return ch, nil
+}
+func (p *poolConn) Close() error { return nil }
+
+func TestRabbitMQConcurrentPublishChannelPool(t *testing.T) {
+	conn := &poolConn{}
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return conn, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{
+		"rabbitmq_channel_pool_size": 4,
+	}))
+	rmq, err := New(cfg)
+	require.NoError(t, err)
+	require.Len(t, conn.chans, 4)
+
+	const publishers = 50
+	var wg sync.WaitGroup
+	for i := 0; i < publishers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			require.NoError(t, rmq.Publish(context.Background(), "q1", []byte(fmt.Sprint(i))))
+		}(i)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, ch := range conn.chans {
+		total += len(ch.published)
+	}
+	require.Equal(t, publishers, total)
+
+	require.NoError(t, rmq.Close())
+	for _, ch := range conn.chans {
+		require.True(t, ch.closed)
+	}
+}
+
+// gobCodec is a non-JSON codec for testing.
+type gobCodec struct{}
+
+func (gobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	return buf.Bytes(), err
+}
+
+func (gobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func TestRabbitMQPublishConsumeCodecMock(t *testing.T) {
+	type msg struct {
+		Name  string
+		Count int
+	}
+
+	ch := &mockChannel{consumeCh: make(chan amqp.Delivery, 1)}
+
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	rmq, err := New(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, Publish(context.Background(), rmq, "q1", gobCodec{}, msg{Name: "hello", Count: 3}))
+	require.Len(t, ch.published, 1)
+	require.False(t, json.Valid(ch.published[0].Body), "body should be gob encoded")
+
+	// Feed the published bytes back through the consumer
+	ch.consumeCh <- amqp.Delivery{Body: ch.published[0].Body}
+	close(ch.consumeCh)
+	out, err := Consume[msg](context.Background(), rmq, "q1", gobCodec{})
+	require.NoError(t, err)
+	require.Equal(t, msg{Name: "hello", Count: 3}, <-out)
+}
+
+func TestRabbitMQErrorSpanStatusMock(t *testing.T) {
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &errConnConsume{}, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{
+		"otel_enabled": true,
+	}))
+	os.Setenv("OTEL_TEST_MOCK_EXPORTER", "true")
+	defer os.Unsetenv("OTEL_TEST_MOCK_EXPORTER")
+	require.NoError(t, otel.Init(cfg))
+	defer otel.Shutdown(context.Background())
+	recorder := tracetest.NewSpanRecorder()
+	otel.GetTracerProvider().RegisterSpanProcessor(recorder)
+
+	rmq, err := New(cfg)
+	require.NoError(t, err)
+	require.Error(t, rmq.Publish(context.Background(), "q1", []byte("x")))
+	_, err = rmq.Consume(context.Background(), "q1")
+	require.Error(t, err)
+
+	statuses := map[string]codes.Code{}
+	for _, s := range recorder.Ended() {
+		statuses[s.Name()] = s.Status().Code
+	}
+	require.Equal(t, codes.Error, statuses["Publish"])
+	require.Equal(t, codes.Error, statuses["Consume"])
+}
+
+type ackCall struct {
+	tag      uint64
+	multiple bool
+}
+
+type mockAcknowledger struct {
+	mu    sync.Mutex
+	acks  []ackCall
+	nacks []ackCall
+}
+
+func (m *mockAcknowledger) Ack(tag uint64, multiple bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.acks = append(m.acks, ackCall{tag, multiple})
+	return nil
+}
+
+func (m *mockAcknowledger) Nack(tag uint64, multiple, requeue bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nacks = append(m.nacks, ackCall{tag, multiple})
+	return nil
+}
+
+func (m *mockAcknowledger) Reject(tag uint64, requeue bool) error { return nil }
+
+func (m *mockAcknowledger) calls() []ackCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]ackCall(nil), m.acks...)
+}
+
+func (m *mockAcknowledger) nackCalls() []ackCall {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]ackCall(nil), m.nacks...)
+}
+
+func TestRabbitMQConsumeBatchAckMock(t *testing.T) {
+	ack := &mockAcknowledger{}
+	ch := &mockChannel{consumeCh: make(chan amqp.Delivery, 4)}
+	for tag := uint64(1); tag <= 4; tag++ {
+		ch.consumeCh <- amqp.Delivery{Acknowledger: ack, DeliveryTag: tag, Body: []byte("m")}
+	}
+
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{
+		"rabbitmq_auto_ack":       false,
+		"rabbitmq_ack_batch_size": 2,
+	}))
+	rmq, err := New(cfg)
+	require.NoError(t, err)
+
+	out, err := rmq.Consume(context.Background(), "q1")
+	require.NoError(t, err)
+
+	// A message is only acked once the next one has been taken
+	<-out
+	<-out
+	require.Empty(t, ack.calls())
+	<-out
+	require.Eventually(t, func() bool { return len(ack.calls()) == 1 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, ackCall{tag: 2, multiple: true}, ack.calls()[0])
+	<-out
+
+	// On stop, handled messages are acked and the last one is left for the
+	// broker to redeliver
+	close(ch.consumeCh)
+	for range out {
+	}
+	require.Equal(t, []ackCall{{2, true}, {3, true}}, ack.calls())
+	require.True(t, ch.closed, "the dedicated consumer channel is closed")
+}
+
+// ackChannel is a mockChannel that, like a broker channel, numbers the
+// deliveries it sends per channel and acknowledges them itself.
+type ackChannel struct {
+	*mockChannel
+	mu      sync.Mutex
+	nextTag uint64
+	unacked map[uint64]bool
+}
+
+func newAckChannel() *ackChannel {
+	return &ackChannel{
+		mockChannel: &mockChannel{consumeCh: make(chan amqp.Delivery, 10)},
+		unacked:     make(map[uint64]bool),
+	}
+}
+
+// deliver sends body to the consumer of the channel.
+func (c *ackChannel) deliver(body string) {
+	c.mu.Lock()
+	c.nextTag++
+	c.unacked[c.nextTag] = true
+	d := amqp.Delivery{Acknowledger: c, DeliveryTag: c.nextTag, Body: []byte(body)}
+	c.mu.Unlock()
+	c.consumeCh <- d
+}
+
+func (c *ackChannel) Ack(tag uint64, multiple bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for t := range c.unacked {
+		if t == tag || (multiple && t < tag) {
+			delete(c.unacked, t)
+		}
+	}
+	return nil
+}
+
+func (c *ackChannel) Nack(tag uint64, multiple, requeue bool) error { return c.Ack(tag, multiple) }
+func (c *ackChannel) Reject(tag uint64, requeue bool) error         { return c.Ack(tag, false) }
+
+func (c *ackChannel) pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.unacked)
+}
+
+// ackConn hands out a fresh ackChannel for every Channel call.
+type ackConn struct{ chans []*ackChannel }
+
+func (a *ackConn) Channel() (amqpChannel, error) {
+	ch := newAckChannel()
+	a.chans = append(a.chans, ch)
+	return ch, nil
+}
+func (a *ackConn) Close() error { return nil }
+
+func TestRabbitMQConsumeBatchAckSeparateChannelsMock(t *testing.T) {
+	conn := &ackConn{}
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return conn, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{
+		"rabbitmq_auto_ack":       false,
+		"rabbitmq_ack_batch_size": 2,
+	}))
+	rmq, err := New(cfg)
+	require.NoError(t, err)
+	require.Len(t, conn.chans, 1)
+
+	// Both consumers start from the single pooled channel
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	orders, err := rmq.Consume(ctx, "orders")
+	require.NoError(t, err)
+	payments, err := rmq.Consume(ctx, "payments")
+	require.NoError(t, err)
+	require.Len(t, conn.chans, 3, "each batch-ack consumer opens its own channel")
+	ordersCh, paymentsCh := conn.chans[1], conn.chans[2]
+
+	paymentsCh.deliver("p1")
+	paymentsCh.deliver("p2")
+	require.Equal(t, "p1", string(<-payments))
+	for _, body := range []string{"o1", "o2", "o3"} {
+		ordersCh.deliver(body)
+	}
+	for range 3 {
+		<-orders
+	}
+	require.Eventually(t, func() bool { return ordersCh.pending() == 1 }, time.Second, 10*time.Millisecond)
+
+	// The orders batch ack left the payments deliveries alone
+	require.Equal(t, 2, paymentsCh.pending())
+	require.Equal(t, 0, conn.chans[0].pending())
+}
+
+func TestRabbitMQInvalidAckBatchSize(t *testing.T) {
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: &mockChannel{}}, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{
+		"rabbitmq_ack_batch_size": -1,
+	}))
+	_, err := New(cfg)
+	require.ErrorContains(t, err, "invalid rabbitmq_ack_batch_size")
+}
+
+func TestRabbitMQPublishTxMock(t *testing.T) {
+	ch := &mockChannel{}
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	rmq, err := New(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, rmq.PublishTx(context.Background(), "q1", [][]byte{[]byte("a"), []byte("b")}))
+	require.Equal(t, []string{"tx", "commit"}, ch.txCalls)
+	require.Len(t, ch.published, 2)
+
+	ch.txCalls = nil
+	ch.publishes = 0
+	ch.publishErr = fmt.Errorf("channel closed")
+	ch.failPublishAt = 2
+	err = rmq.PublishTx(context.Background(), "q1", [][]byte{[]byte("c"), []byte("d"), []byte("e")})
+	require.ErrorContains(t, err, "publish message 1: channel closed")
+	require.Equal(t, []string{"tx", "rollback"}, ch.txCalls)
+	require.Equal(t, 2, ch.publishes, "publishing stops at the first failure")
+}
+
+// notifyConn is a mockConn whose connection and channel report closes.
+type notifyConn struct {
+	mockConn
+	notify chan *amqp.Error
+}
+
+func (c *notifyConn) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	c.notify = receiver
+	return receiver
+}
+
+func (c *notifyConn) Channel() (amqpChannel, error) { return &notifyChannel{mockChannel: c.ch}, nil }
+
+type notifyChannel struct {
+	*mockChannel
+	notify chan *amqp.Error
+}
+
+func (c *notifyChannel) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	c.notify = receiver
+	return receiver
+}
+
+func TestRabbitMQOnCloseMock(t *testing.T) {
+	conn := &notifyConn{mockConn: mockConn{ch: &mockChannel{}}}
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return conn, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New()
+	r, err := New(cfg)
+	require.NoError(t, err)
+	defer r.Close()
+
+	errs := make(chan error, 2)
+	r.OnClose(func(err error) { errs <- err })
+
+	ch := r.channels[0].(*notifyChannel)
+	require.NotNil(t, conn.notify)
+	require.NotNil(t, ch.notify)
+
+	ch.notify <- &amqp.Error{Code: amqp.PreconditionFailed, Reason: "PRECONDITION_FAILED"}
+	select {
+	case err := <-errs:
+		require.Contains(t, err.Error(), "PRECONDITION_FAILED")
+	case <-time.After(time.Second):
+		t.Fatal("callback not called for channel close")
+	}
+
+	conn.notify <- &amqp.Error{Code: amqp.ConnectionForced, Reason: "CONNECTION_FORCED"}
+	select {
+	case err := <-errs:
+		require.Contains(t, err.Error(), "CONNECTION_FORCED")
+	case <-time.After(time.Second):
+		t.Fatal("callback not called for connection close")
+	}
+
+	// A graceful close closes the notification channel without an error.
+	close(conn.notify)
+	select {
+	case err := <-errs:
+		t.Fatalf("unexpected callback: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRabbitMQConsumeWithRedeliveryMock(t *testing.T) {
+	ack := &mockAcknowledger{}
+	ch := &mockChannel{consumeCh: make(chan amqp.Delivery, 4)}
+	dead := make(chan amqp.Publishing, 1)
+	ch.onPublish = func(msg amqp.Publishing) {
+		// Feed requeued messages back to the consumer, as the broker would.
+		if ch.keys[len(ch.keys)-1] == "orders" {
+			ch.consumeCh <- amqp.Delivery{
+				Acknowledger: ack, DeliveryTag: uint64(len(ch.keys) + 1), Body: msg.Body, Headers: msg.Headers,
+				DeliveryMode: msg.DeliveryMode, Priority: msg.Priority, CorrelationId: msg.CorrelationId,
+				ReplyTo: msg.ReplyTo, Expiration: msg.Expiration, MessageId: msg.MessageId,
+			}
+			return
+		}
+		dead <- msg
+	}
+	ch.consumeCh <- amqp.Delivery{
+		Acknowledger: ack, DeliveryTag: 1, Body: []byte("poison"),
+		DeliveryMode: amqp.Persistent, Priority: 5, CorrelationId: "c1",
+		ReplyTo: "replies", Expiration: "60000", MessageId: "m1",
+	}
+
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New()
+	r, err := New(cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var attempts int
+	err = r.ConsumeWithRedelivery(ctx, "orders", func(ctx context.Context, body []byte) error {
+		attempts++
+		return fmt.Errorf("cannot process %s", body)
+	}, RedeliveryOptions{MaxRedeliveries: 2, DeadLetterQueue: "orders.dlq"})
+	require.NoError(t, err)
+
+	select {
+	case msg := <-dead:
+		require.Equal(t, []byte("poison"), msg.Body)
+		require.Equal(t, int32(2), msg.Headers[RedeliveryCountHeader])
+		// Publishing properties survive every redelivery
+		require.Equal(t, amqp.Persistent, msg.DeliveryMode)
+		require.Equal(t, uint8(5), msg.Priority)
+		require.Equal(t, "c1", msg.CorrelationId)
+		require.Equal(t, "replies", msg.ReplyTo)
+		require.Equal(t, "60000", msg.Expiration)
+		require.Equal(t, "m1", msg.MessageId)
+	case <-time.After(time.Second):
+		t.Fatal("message was not dead-lettered")
+	}
+	require.Eventually(t, func() bool { return len(ack.calls()) == 3 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, 3, attempts)
+	require.Equal(t, []string{"orders", "orders", "orders.dlq"}, ch.keys)
+
+	// Successful messages are acked without being republished.
+	ch.consumeCh = make(chan amqp.Delivery, 1)
+	ch.consumeCh <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 10, Body: []byte("ok")}
+	err = r.ConsumeWithRedelivery(ctx, "orders", func(context.Context, []byte) error { return nil }, RedeliveryOptions{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(ack.calls()) == 4 }, time.Second, 10*time.Millisecond)
+	require.Len(t, ch.keys, 3)
+
+	err = r.ConsumeWithRedelivery(ctx, "orders", func(context.Context, []byte) error { return nil }, RedeliveryOptions{MaxRedeliveries: -1})
+	require.Error(t, err)
+}
+
+func TestRabbitMQHandleFuncMock(t *testing.T) {
+	ack := &mockAcknowledger{}
+	ch := &mockChannel{consumeCh: make(chan amqp.Delivery, 2)}
+	ch.consumeCh <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 1, Body: []byte("bad")}
+	ch.consumeCh <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 2, Body: []byte("good")}
+
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New()
+	r, err := New(cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = r.HandleFunc(ctx, "orders", func(ctx context.Context, body []byte) error {
+		if string(body) == "bad" {
+			return fmt.Errorf("cannot process %s", body)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return len(ack.calls()) == 1 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, []ackCall{{tag: 2}}, ack.calls())
+	require.Equal(t, []ackCall{{tag: 1}}, ack.nackCalls())
+	require.Empty(t, ch.keys)
+}
+
+func TestRabbitMQConsumeWithRedeliveryOwnChannelMock(t *testing.T) {
+	conn := &ackConn{}
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return conn, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New()
+	rmq, err := New(cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = rmq.ConsumeWithRedelivery(ctx, "orders", func(context.Context, []byte) error {
+		return fmt.Errorf("failed")
+	}, RedeliveryOptions{MaxRedeliveries: 1})
+	require.NoError(t, err)
+	require.Len(t, conn.chans, 2, "the consumer opens its own channel")
+	pooled, consumer := conn.chans[0], conn.chans[1]
+
+	// The failed message is republished through the pool and acked on the
+	// consumer's channel
+	consumer.deliver("o1")
+	require.Eventually(t, func() bool { return consumer.pending() == 0 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, []string{"orders"}, pooled.keys)
+	require.Empty(t, consumer.published)
+
+	cancel()
+	require.Eventually(t, consumer.isClosed, time.Second, 10*time.Millisecond)
+	require.False(t, pooled.isClosed())
+}
+
+func TestRabbitMQHandleFuncOwnChannelMock(t *testing.T) {
+	conn := &ackConn{}
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return conn, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New()
+	rmq, err := New(cfg)
+	require.NoError(t, err)
+	require.Len(t, conn.chans, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err = rmq.HandleFunc(ctx, "orders", func(context.Context, []byte) error { return nil })
+	require.NoError(t, err)
+	require.Len(t, conn.chans, 2, "the consumer opens its own channel")
+	pooled, consumer := conn.chans[0], conn.chans[1]
+
+	// Publishing goes through the pool, never over the consumer's channel
+	require.NoError(t, rmq.Publish(context.Background(), "orders", []byte("o1")))
+	require.Len(t, pooled.published, 1)
+	require.Empty(t, consumer.published)
+
+	consumer.deliver("o1")
+	require.Eventually(t, func() bool { return consumer.pending() == 0 }, time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.Eventually(t, consumer.isClosed, time.Second, 10*time.Millisecond)
+	require.False(t, pooled.isClosed())
+}
+
+func TestRabbitMQBindQueueMock(t *testing.T) {
+	ch := &mockChannel{consumeCh: make(chan amqp.Delivery)}
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	rmq, err := New(cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, rmq.BindQueue("orders", "events", "order.*"))
+	require.Equal(t, []bindCall{{queue: "orders", key: "order.*", exchange: "events"}}, ch.binds)
+
+	ch.bindErr = fmt.Errorf("no exchange")
+	err = rmq.BindQueue("orders", "missing", "order.*")
+	require.ErrorIs(t, err, ch.bindErr)
+}
+
+func TestRabbitMQDeclareTopologyMock(t *testing.T) {
+	ch := &mockChannel{consumeCh: make(chan amqp.Delivery)}
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	rmq, err := New(cfg)
+	require.NoError(t, err)
+
+	args := amqp.Table{"x-match": "all"}
+	err = rmq.DeclareTopology(Topology{
+		Exchanges: []Exchange{{Name: "events", Kind: "topic", Durable: true}},
+		Queues:    []Queue{{Name: "orders", Options: QueueOptions{Durable: true}}},
+		Bindings: []Binding{
+			{Queue: "orders", Exchange: "events", RoutingKey: "order.created"},
+			{Queue: "orders", Exchange: "events", RoutingKey: "order.updated", Args: args},
+		},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, []exchangeCall{{name: "events", kind: "topic", durable: true}}, ch.exchanges)
+	require.Equal(t, []declareCall{{name: "orders", durable: true}}, ch.declared)
+	require.Equal(t, []bindCall{
+		{queue: "orders", key: "order.created", exchange: "events"},
+		{queue: "orders", key: "order.updated", exchange: "events", args: args},
+	}, ch.binds)
+
+	// Declared queue options are reused by later operations.
+	require.NoError(t, rmq.Publish(context.Background(), "orders", []byte("x")))
+	require.Equal(t, declareCall{name: "orders", durable: true}, ch.declared[1])
+
+	ch.bindErr = fmt.Errorf("bind failed")
+	err = rmq.DeclareTopology(Topology{Bindings: []Binding{{Queue: "orders", Exchange: "events"}}})
+	require.ErrorIs(t, err, ch.bindErr)
+}
+
+func TestRabbitMQPublishBatchConfirmedMock(t *testing.T) {
+	ch := &mockChannel{consumeCh: make(chan amqp.Delivery)}
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{"rabbitmq_confirm_window": 2}))
+	r, err := New(cfg)
+	require.NoError(t, err)
+
+	bodies := [][]byte{[]byte("a"), []byte("b"), []byte("c"), []byte("d"), []byte("e")}
+	require.NoError(t, r.PublishBatchConfirmed(context.Background(), "orders", bodies))
+	require.Len(t, ch.published, 5)
+	require.Equal(t, uint64(5), ch.deliveryTag)
+	// Confirms are awaited per window, so the listener only holds one window.
+	require.Equal(t, 2, cap(ch.confirms))
+	require.Empty(t, ch.confirms)
+
+	ch.nackTags = map[uint64]bool{4: true}
+	err = r.PublishBatchConfirmed(context.Background(), "orders", bodies)
+	require.EqualError(t, err, "message 3 nacked by broker")
+	// The window holding the nacked message is the last one published.
+	require.Len(t, ch.published, 9)
+
+	ch.nackTags = nil
+	ch.confirms = nil
+	ch.published = nil
+	cfg, _ = config.New(config.WithDefault(map[string]interface{}{}))
+	r, err = New(cfg)
+	require.NoError(t, err)
+	require.NoError(t, r.PublishBatchConfirmed(context.Background(), "orders", bodies[:3]))
+	require.Len(t, ch.published, 3)
+	require.Equal(t, 3, cap(ch.confirms))
+
+	cfg, _ = config.New(config.WithDefault(map[string]interface{}{"rabbitmq_confirm_window": -1}))
+	_, err = New(cfg)
+	require.Error(t, err)
+}
+
+func TestRabbitMQPublishDeadlineDuringDeclare(t *testing.T) {
+	ch := &mockChannel{consumeCh: make(chan amqp.Delivery), declareDelay: 200 * time.Millisecond}
+	origDial := dialFunc
+	dialFunc = func(string, amqp.Config) (amqpConn, error) { return &mockConn{ch: ch}, nil }
+	defer func() { dialFunc = origDial }()
+
+	cfg, _ := config.New(config.WithDefault(map[string]interface{}{}))
+	rmq, err := New(cfg)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = rmq.Publish(ctx, "q1", []byte("late"))
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Contains(t, err.Error(), "declare queue")
+	require.Less(t, time.Since(start), 150*time.Millisecond)
+
+	// The channel returns to the pool only after the abandoned declare, so
+	// the next publish waits for it instead of sharing it
+	require.NoError(t, rmq.Publish(context.Background(), "q1", []byte("on time")))
+	require.Len(t, ch.published, 1)
+	require.Equal(t, []byte("on time"), ch.published[0].Body)
+	require.GreaterOrEqual(t, time.Since(start), 400*time.Millisecond)
+
+	// PublishTx and PublishBatchConfirmed declare on a dedicated channel,
+	// which is closed once the abandoned declare returns
+	for name, publish := range map[string]func(context.Context) error{
+		"PublishTx": func(ctx context.Context) error {
+			return rmq.PublishTx(ctx, "q1", [][]byte{[]byte("late")})
+		},
+		"PublishBatchConfirmed": func(ctx context.Context) error {
+			return rmq.PublishBatchConfirmed(ctx, "q1", [][]byte{[]byte("late")})
+		},
+	} {
+		ch.mu.Lock()
+		ch.closed = false
+		ch.mu.Unlock()
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		start := time.Now()
+		err := publish(ctx)
+		cancel()
+		require.ErrorIs(t, err, context.DeadlineExceeded, name)
+		require.Contains(t, err.Error(), "declare queue", name)
+		require.Less(t, time.Since(start), 150*time.Millisecond, name)
+		require.Eventually(t, ch.isClosed, time.Second, 10*time.Millisecond, name)
+	}
+	require.Len(t, ch.published, 1)
+}